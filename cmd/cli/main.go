@@ -16,11 +16,14 @@ func run() error {
 	flag.Parse()
 
 	options := []pkg.Option{}
-	if baseurl != nil {
+	if *baseurl != "" {
 		options = append(options, pkg.WithBaseUrl(*baseurl))
 	}
 
-	apiCall := pkg.NewApiCall(options...)
+	apiCall, err := pkg.New(options...)
+	if err != nil {
+		return err
+	}
 	base, err := apiCall.Send(*method, *url, nil)
 
 	if err != nil {
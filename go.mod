@@ -2,4 +2,8 @@ module github.com/gravataLonga/api-call
 
 go 1.14
 
-require github.com/stretchr/testify v1.6.1
+require (
+	github.com/BurntSushi/toml v0.4.1
+	github.com/stretchr/testify v1.6.1
+	gopkg.in/yaml.v3 v3.0.1
+)
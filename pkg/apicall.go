@@ -1,17 +1,19 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,35 +28,214 @@ type ApiCall struct {
 	// Timeout of duration of request, if reach to limit it will return
 	// BaseStandard response with a Error item
 	Timeout time.Duration
-	ctx     *context.Context
-	cancel  *context.CancelFunc
+	// Validation is how strict the response envelope is checked,
+	// by default it isn't checked at all
+	Validation Validation
+	// Envelope decode every response but problems, which are always
+	// decoded by ProblemEnvelope, when nil it is chosen by response Content-Type
+	Envelope Envelope
+	// ContentType is media type of request body,
+	// its codec is used by SendValue
+	ContentType string
+	// Accept is a list of media types accepted in response,
+	// by order of preference
+	Accept []string
+	// Compression is encoding used to compress request body,
+	// e.g. gzip or deflate
+	Compression string
+	// CompressionThreshold is minimum size of request body to be compressed
+	CompressionThreshold int
+	// Decompression ask for compressed responses and decompress them
+	Decompression bool
+	// RawBody keep whole response body in BaseStandard.RawBody
+	RawBody bool
+	// Redirect is how redirects are followed, when nil
+	// up to 10 redirects are followed to any host
+	Redirect     *RedirectPolicy
+	rateLimiter  *rateLimiter
+	bulkhead     *bulkhead
+	hedger       *hedger
+	retry        *RetryPolicy
+	deduplicator *deduplicator
+	recorder     *Recorder
+	transport    *transport
+	balancer     *balancer
+	ejection     Ejection
+	healthCheck  *HealthCheck
+	checker      *healthChecker
+	lifecycle    *lifecycle
+	// authorization is Authorization header set by WithAuthentication
+	authorization string
+	// strict is set by New, so options check their values
+	strict bool
+	// errs are errors of options, returned by Send
+	errs []error
 }
 
 // Option is a type to make useful of First-Class Function
 // in order to manipulate of ApiCall structure
 type Option func(ApiCall) *ApiCall
 
-// NewApiCall it will create a new ApiCall
+// ConfigError hold every error of options, it is
+// returned by New and Send
+type ConfigError []error
+
+// Error it will join messages of every error
+func (e ConfigError) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is return true when an error of options match target,
+// so errors.Is can look into every error
+func (e ConfigError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As it will set target to first error of options which match
+// it, so errors.As can look into every error
+func (e ConfigError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewApiCall it will create a new ApiCall, errors
+// of options are only returned by Send
 func NewApiCall(options ...Option) *ApiCall {
-	a := &ApiCall{}
+	return newApiCall(false, options)
+}
+
+// New it will create a new ApiCall like NewApiCall, but values of
+// options are checked, e.g. base url must be an absolute http url,
+// and a ConfigError with every invalid option is returned instead
+func New(options ...Option) (*ApiCall, error) {
+	a := newApiCall(true, options)
+	if len(a.errs) > 0 {
+		return nil, ConfigError(a.errs)
+	}
+	return a, nil
+}
+
+func newApiCall(strict bool, options []Option) *ApiCall {
+	a := &ApiCall{strict: strict}
 	a.Headers = make(http.Header)
+	a.lifecycle = new(lifecycle)
 	for _, option := range options {
 		a = option(*a)
 	}
+	if strict {
+		a.checkAuthentication()
+	}
+	if len(a.errs) == 0 {
+		a.startHealthCheck()
+	}
 	return a
 }
 
-// WithBaseUrl it will modified ApiCall.BaseUrl field
+// With it will return a copy of ApiCall with options applied, Headers,
+// Accept and Redirect are copied so options don't change ApiCall, while
+// transport, connections, rate limiter and bulkhead are shared, the copy
+// has its own deduplication and lifecycle, so its Close only wait for its
+// own requests and keep connections of ApiCall, it is safe to call concurrently
+func (a *ApiCall) With(options ...Option) *ApiCall {
+	derived := *a
+	derived.lifecycle = &lifecycle{shared: a.transport}
+	derived.deduplicator = a.deduplicator.fork()
+	derived.Headers = a.Headers.Clone()
+	if derived.Headers == nil {
+		derived.Headers = make(http.Header)
+	}
+	derived.Accept = append([]string(nil), a.Accept...)
+	derived.errs = append([]error(nil), a.errs...)
+	if a.Redirect != nil {
+		redirect := *a.Redirect
+		derived.Redirect = &redirect
+	}
+
+	d := &derived
+	for _, option := range options {
+		d = option(*d)
+	}
+	if d.strict {
+		d.checkAuthentication()
+	}
+	if d.checksOtherEndpoints(a) {
+		d.checker = nil
+		if len(d.errs) == 0 {
+			d.startHealthCheck()
+		}
+	}
+	return d
+}
+
+// checksOtherEndpoints return true when health check of a
+// isn't the one of original, e.g. when base url was changed
+func (a *ApiCall) checksOtherEndpoints(original *ApiCall) bool {
+	if a.healthCheck == nil {
+		return a.checker != nil
+	}
+	return a.checker == nil ||
+		a.healthCheck != original.healthCheck ||
+		a.balancer != original.balancer ||
+		a.transport != original.transport ||
+		(a.balancer == nil && a.BaseUrl != original.BaseUrl)
+}
+
+// WithBaseUrl it will modified ApiCall.BaseUrl field, when base
+// is an unix socket, e.g. unix:///var/run/api.sock, requests are
+// sent over that socket
 func WithBaseUrl(base string) Option {
 	return func(a ApiCall) *ApiCall {
+		if path, ok := unixSocket(base); ok {
+			a.BaseUrl = unixBaseUrl
+			return WithUnixSocket(path)(a)
+		}
+		if err := validateBaseUrl(base); err != nil && a.strict {
+			a.addError(err)
+			return &a
+		}
 		a.BaseUrl = base
 		return &a
 	}
 }
 
+// validateBaseUrl return an error when base isn't an absolute http url
+func validateBaseUrl(base string) error {
+	if base == "" {
+		return errors.New("base url: must not be empty")
+	}
+	parsed, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("base url: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("base url: unsupported scheme %q in %s", parsed.Scheme, base)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("base url: missing host in %s", base)
+	}
+	return nil
+}
+
 // WithTimeout it will modified ApiCall.Timeout field
 func WithTimeout(duration time.Duration) Option {
 	return func(a ApiCall) *ApiCall {
+		if a.strict && duration < 0 {
+			a.addError(fmt.Errorf("timeout: must not be negative, got %v", duration))
+			return &a
+		}
 		a.Timeout = duration
 		return &a
 	}
@@ -63,8 +244,22 @@ func WithTimeout(duration time.Duration) Option {
 // WithAuthentication it will create a basic authentication bearer
 func WithAuthentication(username, password string) Option {
 	return func(a ApiCall) *ApiCall {
+		if a.strict && username == "" {
+			a.addError(errors.New("authentication: username must not be empty"))
+			return &a
+		}
 		encode := base64.URLEncoding.EncodeToString([]byte(username + ":" + password))
-		a.Headers.Add("Authorization", "Basic "+encode)
+		a.authorization = "Basic " + encode
+		a.Headers = a.Headers.Clone()
+		a.Headers.Add("Authorization", a.authorization)
+		return &a
+	}
+}
+
+// WithRawBody it will keep whole response body in BaseStandard.RawBody
+func WithRawBody() Option {
+	return func(a ApiCall) *ApiCall {
+		a.RawBody = true
 		return &a
 	}
 }
@@ -72,14 +267,91 @@ func WithAuthentication(username, password string) Option {
 // Send it will send a request and parse response in order to
 // be compatible with BaseStandard
 func (a *ApiCall) Send(method, url string, body io.Reader) (*BaseStandard, error) {
+	return a.SendWithContext(context.Background(), method, url, body)
+}
+
+// SendWithContext it will send a request like Send, request
+// is canceled when ctx is done
+func (a *ApiCall) SendWithContext(ctx context.Context, method, url string, body io.Reader) (*BaseStandard, error) {
+	return a.sendWithHeaders(ctx, method, url, body, nil)
+}
+
+func (a *ApiCall) sendWithHeaders(ctx context.Context, method, url string, body io.Reader, overrides http.Header) (*BaseStandard, error) {
+	if len(a.errs) > 0 {
+		return nil, ConfigError(a.errs)
+	}
+	if err := a.lifecycle.enter(); err != nil {
+		return nil, err
+	}
+	defer a.lifecycle.leave()
+	headers := a.requestHeaders()
+	overrideHeaders(headers, overrides)
+	if a.deduplicator.accept(method, body) {
+		key := a.deduplicator.key(method, a.BaseUrl+url, headers, overrides)
+		return a.deduplicator.do(ctx, key, func(ctx context.Context) (*BaseStandard, error) {
+			// shared request outlive its first caller, so it is tracked on its own
+			if err := a.lifecycle.enter(); err != nil {
+				return nil, err
+			}
+			defer a.lifecycle.leave()
+			return a.send(ctx, method, url, body, headers)
+		}, func(err error) *BaseStandard {
+			return formatExceptionResponse(newBaseStandard(a), nil, err)
+		})
+	}
+	return a.send(ctx, method, url, body, headers)
+}
+
+// send it will make request and pack its response into BaseStandard
+func (a *ApiCall) send(ctx context.Context, method, url string, body io.Reader, headers http.Header) (*BaseStandard, error) {
 	var baseResponse = newBaseStandard(a)
-	response, err := makeRequest(*a.ctx, method, a.BaseUrl+url, body, a.Headers)
+	if a.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.Timeout)
+		defer cancel()
+	}
+
+	body, err := compressRequest(a, baseResponse, body, headers)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := a.balancer.pick()
+	base := a.BaseUrl
+	if endpoint != nil {
+		base = endpoint.url
+		baseResponse.AuditInfo.Endpoint = base
+	}
+	host := hostOf(base + url)
+	err = a.rateLimiter.wait(ctx, host)
+	if err != nil {
+		a.balancer.release(endpoint)
+		return formatExceptionResponse(baseResponse, nil, err), nil
+	}
+	err = a.bulkhead.acquire(ctx)
+	if err != nil {
+		a.balancer.release(endpoint)
+		return formatExceptionResponse(baseResponse, nil, err), nil
+	}
+	defer a.bulkhead.release()
+	response, err := a.retry.do(ctx, method, body, func(body io.Reader) (*http.Response, error) {
+		return a.hedger.do(ctx, method, body, func(ctx context.Context) (*http.Response, error) {
+			return makeRequest(ctx, a.client(), method, base+url, body, headers.Clone())
+		})
+	})
+	if endpointCanceled(ctx, err) {
+		a.balancer.release(endpoint)
+	} else {
+		a.balancer.done(endpoint, endpointFailed(response, err), a.ejection)
+	}
 
 	if err != nil {
 		return formatExceptionResponse(baseResponse, response, err), nil
 	}
 
-	err = formatResponse(baseResponse, response)
+	defer response.Body.Close()
+	a.rateLimiter.observe(host, response)
+
+	err = formatResponse(a, baseResponse, response)
 	if err != nil {
 		return nil, err
 	}
@@ -87,15 +359,87 @@ func (a *ApiCall) Send(method, url string, body io.Reader) (*BaseStandard, error
 	return baseResponse, nil
 }
 
+// SendValue it will encode value with codec of ApiCall.ContentType
+// and send it as request body
+func (a *ApiCall) SendValue(method, url string, value interface{}) (*BaseStandard, error) {
+	codec, ok := codecFor(a.contentType())
+	if !ok {
+		return nil, fmt.Errorf("no codec registered for %s", a.contentType())
+	}
+	body, err := codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return a.Send(method, url, bytes.NewReader(body))
+}
+
+// requestHeaders return a copy of ApiCall.Headers with Content-Type,
+// Accept and User-Agent of request, so Send never change ApiCall.Headers
+func (a *ApiCall) requestHeaders() http.Header {
+	headers := a.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	if _, ok := headers["User-Agent"]; !ok {
+		headers.Set("User-Agent", defaultUserAgent)
+	}
+	if _, ok := headers["Content-Type"]; !ok {
+		headers.Set("Content-Type", a.contentType())
+	}
+	if len(a.Accept) > 0 {
+		headers.Set("Accept", acceptHeader(a.Accept))
+	}
+	if a.Decompression {
+		headers.Set("Accept-Encoding", acceptEncoding())
+	}
+	return headers
+}
+
+func (a *ApiCall) contentType() string {
+	if a.ContentType == "" {
+		return "application/json; charset=UTF-8"
+	}
+	return a.ContentType
+}
+
+// client return http client which send requests
+func (a *ApiCall) client() *http.Client {
+	transport := a.transport.roundTripper()
+	if a.recorder != nil {
+		transport = &recorderTransport{recorder: a.recorder, next: transport}
+	}
+	policy := defaultRedirectPolicy
+	if a.Redirect != nil {
+		policy = *a.Redirect
+	}
+	return &http.Client{Transport: transport, CheckRedirect: policy.checkRedirect}
+}
+
+// checkAuthentication it will refuse another Authorization header
+// besides the one of WithAuthentication, whatever order of options
+func (a *ApiCall) checkAuthentication() {
+	if a.authorization == "" {
+		return
+	}
+	if values := a.Headers["Authorization"]; len(values) != 1 || values[0] != a.authorization {
+		a.addError(errors.New("authentication: Authorization header is already set"))
+	}
+}
+
+// addError it will keep err of an option, without
+// changing errors of ApiCall it was copied from
+func (a *ApiCall) addError(err error) {
+	a.errs = append(append([]error(nil), a.errs...), err)
+}
+
 // makeRequest is a function used internally only to make request
-func makeRequest(ctx context.Context, method, url string, body io.Reader, headers http.Header) (*http.Response, error) {
+func makeRequest(ctx context.Context, client *http.Client, method, url string, body io.Reader, headers http.Header) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header = headers
-	req.Header.Add("Content-Type", "application/json; charset=UTF-8")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -103,20 +447,12 @@ func makeRequest(ctx context.Context, method, url string, body io.Reader, header
 }
 
 func newBaseStandard(a *ApiCall) *BaseStandard {
-	ctx := context.Background()
 	// Base Settings for MakingRequest
 	var baseResponse = new(BaseStandard)
 
 	baseResponse.AuditInfo.Host, _ = os.Hostname()
 	baseResponse.AuditInfo.Timestamp = time.Now()
 	baseResponse.AuditInfo.ClientIP, _ = externalIP()
-	var cancel context.CancelFunc
-	if a.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, a.Timeout)
-	}
-
-	a.ctx = &ctx
-	a.cancel = &cancel
 	operationId, _ := baseResponse.newOperationId()
 	baseResponse.AuditInfo.OperationId = operationId
 
@@ -124,21 +460,48 @@ func newBaseStandard(a *ApiCall) *BaseStandard {
 }
 
 // formatResponse it will pack raw response into our structure
-func formatResponse(baseResponse *BaseStandard, response *http.Response) error {
-	binary, err := ioutil.ReadAll(response.Body)
+func formatResponse(a *ApiCall, baseResponse *BaseStandard, response *http.Response) error {
+	binary, compressedSize, err := readResponse(a, response)
 	if err != nil {
 		return err
 	}
-	err = json.Unmarshal(binary, baseResponse)
-	if err != nil {
-		baseResponse.Errors.Items = append(baseResponse.Errors.Items, fallbackResponse(binary, err))
+	contentType := response.Header.Get("Content-Type")
+	if codec, ok := codecFor(contentType); ok && codec != JSONCodec {
+		err = decodeWithCodec(a, codec, binary, baseResponse)
+		if err != nil {
+			baseResponse.Errors.Items = append(baseResponse.Errors.Items, fallbackResponse(binary, err))
+		}
+	} else {
+		decodeEnvelope(a, envelopeFor(a, contentType), binary, baseResponse)
 	}
 
 	baseResponse.AuditInfo.StatusCode = response.StatusCode
+	baseResponse.Status = response.Status
+	baseResponse.Proto = response.Proto
+	baseResponse.Header = response.Header
+	baseResponse.URL = response.Request.URL.String()
+	baseResponse.AuditInfo.Redirects = redirects(response)
+	if a.RawBody {
+		baseResponse.RawBody = binary
+	}
+	baseResponse.AuditInfo.ResponseSize = int64(len(binary))
+	baseResponse.AuditInfo.ResponseCompressedSize = compressedSize
 
 	return nil
 }
 
+// decodeEnvelope it will decode json raw into baseResponse,
+// BaseStandard envelope is also validated
+func decodeEnvelope(a *ApiCall, envelope Envelope, raw []byte, baseResponse *BaseStandard) {
+	err := envelope.Decode(raw, baseResponse)
+	if err != nil {
+		baseResponse.Errors.Items = append(baseResponse.Errors.Items, fallbackResponse(raw, err))
+	}
+	if _, ok := envelope.(standardEnvelope); ok {
+		validateEnvelope(baseResponse, raw, a.Validation)
+	}
+}
+
 func fallbackResponse(r []byte, err error) Meta {
 	if e, ok := err.(*json.SyntaxError); ok {
 		return Meta{
@@ -153,8 +516,11 @@ func fallbackResponse(r []byte, err error) Meta {
 	}
 }
 
+// formatExceptionResponse it will add err into errors of baseResponse,
+// errors of this package have their own code, any other
+// is only described, e.g. errors of proxy or resolver
 func formatExceptionResponse(baseResponse *BaseStandard, response *http.Response, err error) *BaseStandard {
-	var meta Meta
+	meta := Meta{Description: err.Error()}
 	if errors.Is(err, context.DeadlineExceeded) {
 		meta.Code = "1"
 		meta.Description = "Timeout"
@@ -165,6 +531,27 @@ func formatExceptionResponse(baseResponse *BaseStandard, response *http.Response
 		meta.Description = "Canceled"
 	}
 
+	if errors.Is(err, errQueueFull) {
+		meta.Code = "3"
+		meta.Description = "Queue full"
+	}
+
+	if errors.Is(err, ErrTooManyRedirects) {
+		meta.Code = "5"
+	}
+
+	if errors.Is(err, ErrCrossHostRedirect) {
+		meta.Code = "6"
+	}
+
+	if errors.Is(err, ErrPinMismatch) {
+		meta.Code = "7"
+	}
+
+	if unmatched, ok := unmatchedRequestMeta(err); ok {
+		meta = unmatched
+	}
+
 	baseResponse.Errors.Items = append(baseResponse.Errors.Items, meta)
 	return baseResponse
 }
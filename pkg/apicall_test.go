@@ -1,7 +1,9 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -22,11 +24,8 @@ func TestPassingOptions(t *testing.T) {
 				WithBaseUrl("https://google.pt"),
 			},
 			ApiCall{
-				http.Header{},
-				"https://google.pt",
-				0,
-				nil,
-				nil,
+				Headers: http.Header{},
+				BaseUrl: "https://google.pt",
 			},
 		},
 	}
@@ -367,3 +366,249 @@ func TestCanParseAuditInfo(t *testing.T) {
 	assert.Empty(t, response.AuditInfo.Warning.Items)
 	assert.Empty(t, response.AuditInfo.Info.Items)
 }
+
+func TestKeepRawResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/old" {
+			http.Redirect(writer, request, "/new", http.StatusMovedPermanently)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("ETag", `"abc"`)
+		writer.Header().Set("X-RateLimit-Remaining", "10")
+		writer.WriteHeader(http.StatusCreated)
+		_, _ = writer.Write([]byte(`{"items":[{"echo":"Hello World"}]}`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/old", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, "201 Created", response.Status)
+	assert.Equal(t, "HTTP/1.1", response.Proto)
+	assert.Equal(t, `"abc"`, response.Header.Get("ETag"))
+	assert.Equal(t, "10", response.Header.Get("X-RateLimit-Remaining"))
+	assert.Equal(t, ts.URL+"/new", response.URL)
+	assert.Nil(t, response.RawBody)
+}
+
+func TestKeepRawBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		_, _ = writer.Write([]byte("Hello World"))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRawBody(),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello World", string(response.RawBody))
+}
+
+func TestNewReturnErrorsOfEveryOption(t *testing.T) {
+	apicall, err := New(
+		WithBaseUrl("ftp://google.pt"),
+		WithTimeout(-time.Second),
+		WithMaxConcurrency(0),
+		WithHedging(0),
+	)
+
+	assert.Nil(t, apicall)
+	assert.EqualError(t, err, `base url: unsupported scheme "ftp" in ftp://google.pt; timeout: must not be negative, got -1s; concurrency: must be at least 1, got 0; hedging: delay must be positive, got 0s`)
+	assert.Len(t, err.(ConfigError), 4)
+}
+
+func TestNewValidateOptions(t *testing.T) {
+	tables := []struct {
+		option Option
+		error  string
+	}{
+		{WithBaseUrl(""), "base url: must not be empty"},
+		{WithBaseUrl("/users"), `base url: unsupported scheme "" in /users`},
+		{WithBaseUrl("http://"), "base url: missing host in http://"},
+		{WithBaseUrls([]string{"http://a", "b"}, nil), `base url: unsupported scheme "" in b`},
+		{WithAuthentication("", "secret"), "authentication: username must not be empty"},
+		{WithContentType("application/json;;"), "content type: mime: invalid media parameter"},
+		{WithAccept("/json"), "accept: mime: no media type"},
+		{WithCompression("gzip", -1), "compression: threshold must not be negative, got -1"},
+		{WithCompression("zstd", 0), "compression: no compressor registered for zstd"},
+		{WithEnvelope(nil), "envelope: must not be nil"},
+		{WithValidation(Validation(9)), "validation: unknown validation 9"},
+		{WithRateLimit(RateLimit{}), "rate limit: PerSecond must be positive unless it is Adaptive"},
+		{WithRecorder(nil), "recorder: must not be nil"},
+		{WithRedirectPolicy(RedirectPolicy{MaxRedirects: -1}), "redirect: MaxRedirects must not be negative, got -1"},
+		{WithUnixSocket(""), "unix socket: path must not be empty"},
+		{WithResolver(Resolver{Hosts: map[string][]string{"api": {"localhost"}}}), `resolver: invalid address "localhost" of api`},
+		{WithPinnedKeys("abc"), `pinned keys: "abc" isn't base64 of a SHA-256`},
+		{WithHealthCheck(HealthCheck{Path: "health"}), `health check: path must start with /, got "health"`},
+		{WithHealthCheck(HealthCheck{Path: "/health", Interval: -time.Second}), "health check: interval, timeout and thresholds must not be negative"},
+		{WithEjection(Ejection{Duration: -time.Second}), "ejection: duration must not be negative, got -1s"},
+		{WithRetry(RetryPolicy{MaxRetries: -1}), "retry: MaxRetries must not be negative, got -1"},
+		{WithRetry(RetryPolicy{Backoff: -time.Second}), "retry: Backoff and MaxBackoff must not be negative"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.error, func(t *testing.T) {
+			_, err := New(table.option)
+
+			assert.EqualError(t, err, table.error)
+		})
+	}
+}
+
+func TestNewApiCallDoesNotCheckValues(t *testing.T) {
+	apicall := NewApiCall(
+		WithBaseUrl("/api"),
+		WithAuthentication("", "secret"),
+		WithBulkhead(0, 0),
+	)
+
+	assert.Empty(t, apicall.errs)
+	assert.Equal(t, "/api", apicall.BaseUrl)
+	assert.Equal(t, "Basic OnNlY3JldA==", apicall.Headers.Get("Authorization"))
+	assert.Equal(t, 1, cap(apicall.bulkhead.slots))
+}
+
+func TestConfigErrorMatchEveryError(t *testing.T) {
+	_, err := New(WithTimeout(-time.Second), WithCACert("/missing/ca.pem"))
+
+	var pathError *os.PathError
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	assert.True(t, errors.As(err, &pathError))
+	assert.Equal(t, "/missing/ca.pem", pathError.Path)
+	assert.False(t, errors.Is(err, ErrClosed))
+}
+
+func TestNewRefuseConflictingAuthentication(t *testing.T) {
+	tables := []struct {
+		name    string
+		options []Option
+	}{
+		{"twice", []Option{WithAuthentication("jonathan", "12345678"), WithAuthentication("other", "12345678")}},
+		{"header before", []Option{WithHeader("Authorization", "Bearer abc"), WithAuthentication("jonathan", "12345678")}},
+		{"header after", []Option{WithAuthentication("jonathan", "12345678"), WithHeader("Authorization", "Bearer abc")}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := New(table.options...)
+
+			assert.EqualError(t, err, "authentication: Authorization header is already set")
+		})
+	}
+
+	apicall, _ := New(WithAuthentication("jonathan", "12345678"))
+	_, err := apicall.With(WithHeader("Authorization", "Bearer abc")).Send("GET", "/", nil)
+	assert.EqualError(t, err, "authentication: Authorization header is already set")
+}
+
+func TestNewWithValidOptions(t *testing.T) {
+	apicall, err := New(WithBaseUrl("https://google.pt"), WithTimeout(time.Second))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://google.pt", apicall.BaseUrl)
+}
+
+func TestWithDoesNotChangeOriginal(t *testing.T) {
+	original := NewApiCall(WithBaseUrl("https://google.pt"), WithAccept("application/json"))
+	original.Headers.Set("X-Team", "core")
+
+	derived := original.With(
+		WithAuthentication("tenant", "secret"),
+		func(a ApiCall) *ApiCall {
+			a.Headers.Set("X-Team", "tenant")
+			a.Accept[0] = "application/xml"
+			return &a
+		},
+	)
+
+	assert.Equal(t, "core", original.Headers.Get("X-Team"))
+	assert.Empty(t, original.Headers.Get("Authorization"))
+	assert.Equal(t, []string{"application/json"}, original.Accept)
+	assert.Equal(t, "tenant", derived.Headers.Get("X-Team"))
+	assert.NotEmpty(t, derived.Headers.Get("Authorization"))
+	assert.Equal(t, "https://google.pt", derived.BaseUrl)
+}
+
+func TestWithShareTransport(t *testing.T) {
+	original := NewApiCall(WithBaseUrl("https://google.pt"), WithMinTLSVersion(0x0303))
+
+	derived := original.With(WithTimeout(time.Second))
+	tls := original.With(WithMinTLSVersion(0x0304))
+
+	assert.Same(t, original.transport, derived.transport)
+	assert.NotSame(t, original.lifecycle, derived.lifecycle)
+	assert.NotSame(t, original.transport, tls.transport)
+	assert.Equal(t, uint16(0x0303), original.transport.config.tls.MinVersion)
+}
+
+func TestWithReturnErrorsOfOptions(t *testing.T) {
+	original, _ := New(WithBaseUrl("https://google.pt"))
+
+	derived := original.With(WithTimeout(-time.Second))
+	_, err := derived.Send("GET", "/", nil)
+
+	assert.EqualError(t, err, "timeout: must not be negative, got -1s")
+	assert.Empty(t, original.errs)
+}
+
+func TestWithIsSafeConcurrently(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(writer, `{"items":[%q]}`, request.Header.Get("X-Tenant"))
+	}))
+	defer ts.Close()
+	original := NewApiCall(WithBaseUrl(ts.URL))
+
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		go func(tenant string) {
+			derived := original.With(func(a ApiCall) *ApiCall {
+				a.Headers.Set("X-Tenant", tenant)
+				return &a
+			})
+			response, err := derived.Send("GET", "/", nil)
+			if err == nil && string(*response.Items) != `["`+tenant+`"]` {
+				err = fmt.Errorf("expected tenant %s, got %s", tenant, *response.Items)
+			}
+			errs <- err
+		}(fmt.Sprint(i))
+	}
+
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, <-errs)
+	}
+	assert.Empty(t, original.Headers)
+}
+
+func TestWithHasItsOwnLifecycle(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[1]}`))
+	}))
+	defer ts.Close()
+	original := NewApiCall(WithBaseUrl(ts.URL), WithDeduplication())
+	first := original.With(WithHeader("X-Tenant", "acme"))
+	second := original.With(WithHeader("X-Tenant", "globex"))
+
+	assert.Nil(t, first.Close(context.Background()))
+	_, closed := first.Send("GET", "/", nil)
+	fromOriginal, err := original.Send("GET", "/", nil)
+	fromSecond, secondErr := second.Send("GET", "/", nil)
+
+	assert.Equal(t, ErrClosed, closed)
+	assert.Nil(t, err)
+	assert.True(t, fromOriginal.IsOk())
+	assert.Nil(t, secondErr)
+	assert.True(t, fromSecond.IsOk())
+	assert.NotSame(t, original.deduplicator, first.deduplicator)
+	assert.Equal(t, original.deduplicator.headers, first.deduplicator.headers)
+}
@@ -0,0 +1,314 @@
+// Package apicalltest provide a programmable fake server
+// of BaseStandard APIs to test code which use ApiCall
+package apicalltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravataLonga/api-call/pkg"
+)
+
+// Call is a request received by Server
+type Call struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// String it will format call as "METHOD /path"
+func (c Call) String() string {
+	return c.Method + " " + c.Path
+}
+
+// Server is a fake server which respond with BaseStandard envelopes,
+// Close it will stop server and assert its expectations
+type Server struct {
+	*httptest.Server
+	t      testing.TB
+	mu     sync.Mutex
+	routes []*Route
+	calls  []Call
+}
+
+// NewServer it will start a Server for test t, it must be
+// closed when test ends, e.g. defer server.Close()
+func NewServer(t testing.TB) *Server {
+	s := &Server{t: t}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Close it will stop server and check every
+// route which expect a number of calls
+func (s *Server) Close() {
+	s.Server.Close()
+	s.AssertExpectations(s.t)
+}
+
+// On it will register a route for method and path, by default it
+// respond with 200 and empty items
+func (s *Server) On(method, path string) *Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	route := &Route{method: method, path: path, header: make(http.Header), status: http.StatusOK, items: []interface{}{}, times: -1}
+	s.routes = append(s.routes, route)
+	return route
+}
+
+// Calls return every request received, by order of arrival
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// AssertCalled it will check that method and path was called times
+func (s *Server) AssertCalled(t testing.TB, method, path string, times int) bool {
+	t.Helper()
+	called := 0
+	for _, call := range s.Calls() {
+		if call.Method == method && call.Path == path {
+			called++
+		}
+	}
+	if called != times {
+		t.Errorf("apicalltest: expected %s %s to be called %d times, but was called %d times", method, path, times, called)
+		return false
+	}
+	return true
+}
+
+// AssertOrder it will check that calls were received in given
+// order, e.g. AssertOrder(t, "POST /login", "GET /users")
+func (s *Server) AssertOrder(t testing.TB, calls ...string) bool {
+	t.Helper()
+	received := make([]string, 0, len(s.Calls()))
+	for _, call := range s.Calls() {
+		received = append(received, call.String())
+	}
+	if !reflect.DeepEqual(received, calls) {
+		t.Errorf("apicalltest: expected calls %v, but received %v", calls, received)
+		return false
+	}
+	return true
+}
+
+// AssertExpectations it will check every route which
+// expect a number of calls with Times
+func (s *Server) AssertExpectations(t testing.TB) bool {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok := true
+	for _, route := range s.routes {
+		if route.times >= 0 && route.calls != route.times {
+			t.Errorf("apicalltest: expected %s %s to be called %d times, but was called %d times", route.method, route.path, route.times, route.calls)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (s *Server) handle(writer http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+	call := Call{Method: request.Method, Path: request.URL.Path, Header: request.Header.Clone(), Body: body}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	route, mismatch := s.match(call)
+	if route != nil {
+		route.calls++
+	}
+	s.mu.Unlock()
+
+	if route == nil {
+		s.t.Errorf("apicalltest: unexpected request %s%s", call, mismatch)
+		writeEnvelope(writer, http.StatusNotFound, nil, pkg.AuditInfo{
+			Errors: pkg.Items{Items: pkg.ItemsMeta{{Code: "404", Description: "Unexpected request " + call.String()}}},
+		})
+		return
+	}
+	route.respond(writer, request)
+}
+
+// match return first route which match call, or why
+// routes for same method and path didn't match
+func (s *Server) match(call Call) (*Route, string) {
+	var mismatches []string
+	for _, route := range s.routes {
+		if route.method != call.Method || route.path != call.Path {
+			continue
+		}
+		mismatch := route.mismatch(call)
+		if mismatch == "" {
+			return route, ""
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	if len(mismatches) == 0 {
+		return nil, ""
+	}
+	return nil, ": " + strings.Join(mismatches, ", ")
+}
+
+// Route is a request expected by Server and its response
+type Route struct {
+	method    string
+	path      string
+	header    http.Header
+	body      []byte
+	status    int
+	items     interface{}
+	auditInfo pkg.AuditInfo
+	latency   time.Duration
+	hang      bool
+	malformed bool
+	times     int
+	calls     int
+}
+
+// WithHeader it will only match requests with header key equal to value
+func (r *Route) WithHeader(key, value string) *Route {
+	r.header.Add(key, value)
+	return r
+}
+
+// WithBody it will only match requests with body, json
+// bodies are compared regardless of formatting
+func (r *Route) WithBody(body string) *Route {
+	r.body = []byte(body)
+	return r
+}
+
+// Status it will respond with status code
+func (r *Route) Status(code int) *Route {
+	r.status = code
+	return r
+}
+
+// Items it will respond with items, which is encoded as json
+func (r *Route) Items(items interface{}) *Route {
+	r.items = items
+	return r
+}
+
+// AuditInfo it will respond with auditInfo changed by build
+func (r *Route) AuditInfo(build func(auditInfo *pkg.AuditInfo)) *Route {
+	build(&r.auditInfo)
+	return r
+}
+
+// Error it will add an error into auditInfo of response
+func (r *Route) Error(code, description string) *Route {
+	r.auditInfo.Errors.Items = append(r.auditInfo.Errors.Items, pkg.Meta{Code: code, Description: description})
+	return r
+}
+
+// Warning it will add a warning into auditInfo of response
+func (r *Route) Warning(code, description string) *Route {
+	r.auditInfo.Warning.Items = append(r.auditInfo.Warning.Items, pkg.Meta{Code: code, Description: description})
+	return r
+}
+
+// Info it will add an info into auditInfo of response
+func (r *Route) Info(code, description string) *Route {
+	r.auditInfo.Info.Items = append(r.auditInfo.Info.Items, pkg.Meta{Code: code, Description: description})
+	return r
+}
+
+// Latency it will wait duration before respond
+func (r *Route) Latency(duration time.Duration) *Route {
+	r.latency = duration
+	return r
+}
+
+// Timeout it will never respond, until client give up
+func (r *Route) Timeout() *Route {
+	r.hang = true
+	return r
+}
+
+// Malformed it will respond with an invalid json
+func (r *Route) Malformed() *Route {
+	r.malformed = true
+	return r
+}
+
+// Times it will expect route to be called n times,
+// which is asserted by Close
+func (r *Route) Times(n int) *Route {
+	r.times = n
+	return r
+}
+
+func (r *Route) mismatch(call Call) string {
+	for key, values := range r.header {
+		received := call.Header[http.CanonicalHeaderKey(key)]
+		if !reflect.DeepEqual(received, values) {
+			return fmt.Sprintf("header %s is %q, expected %q", key, received, values)
+		}
+	}
+	if r.body != nil && !equalBody(r.body, call.Body) {
+		return fmt.Sprintf("body is %s, expected %s", call.Body, r.body)
+	}
+	return ""
+}
+
+func (r *Route) respond(writer http.ResponseWriter, request *http.Request) {
+	if r.hang {
+		<-request.Context().Done()
+		return
+	}
+	if r.latency > 0 {
+		select {
+		case <-time.After(r.latency):
+		case <-request.Context().Done():
+			return
+		}
+	}
+	if r.malformed {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(r.status)
+		_, _ = writer.Write([]byte(`{"items":[`))
+		return
+	}
+	writeEnvelope(writer, r.status, r.items, r.auditInfo)
+}
+
+func writeEnvelope(writer http.ResponseWriter, status int, items interface{}, auditInfo pkg.AuditInfo) {
+	envelope := pkg.BaseStandard{AuditInfo: auditInfo}
+	binary, err := json.Marshal(items)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	raw := json.RawMessage(binary)
+	envelope.Items = &raw
+	response, err := json.Marshal(envelope)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	_, _ = writer.Write(response)
+}
+
+func equalBody(expected, actual []byte) bool {
+	var expectedJson, actualJson interface{}
+	if json.Unmarshal(expected, &expectedJson) == nil && json.Unmarshal(actual, &actualJson) == nil {
+		return reflect.DeepEqual(expectedJson, actualJson)
+	}
+	return bytes.Equal(expected, actual)
+}
@@ -0,0 +1,109 @@
+package apicalltest
+
+import (
+	"context"
+	"github.com/gravataLonga/api-call/pkg"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestServerRespondWithTypedItems(t *testing.T) {
+	server := NewServer(t)
+	defer server.Close()
+	server.On("POST", "/users").
+		WithHeader("Content-Type", "application/json; charset=UTF-8").
+		WithBody(`{ "name": "Jonathan" }`).
+		Status(201).
+		Items([]user{{Name: "Jonathan"}}).
+		Warning("x01", "Deprecated").
+		AuditInfo(func(auditInfo *pkg.AuditInfo) {
+			auditInfo.OperationId = "abc"
+		}).
+		Times(1)
+
+	apicall := pkg.NewApiCall(pkg.WithBaseUrl(server.URL))
+	response, err := apicall.Send("POST", "/users", strings.NewReader(`{"name":"Jonathan"}`))
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, 201, response.StatusCode)
+	assert.Equal(t, "abc", response.OperationId)
+	assert.Equal(t, "x01", response.Warning.Items[0].Code)
+	var users []user
+	assert.Nil(t, response.GetItems(&users))
+	assert.Equal(t, []user{{Name: "Jonathan"}}, users)
+}
+
+func TestServerRespondWithErrors(t *testing.T) {
+	server := NewServer(t)
+	defer server.Close()
+	server.On("GET", "/users").Status(422).Error("x02", "Invalid")
+
+	apicall := pkg.NewApiCall(pkg.WithBaseUrl(server.URL))
+	response, _ := apicall.Send("GET", "/users", nil)
+
+	assert.False(t, response.IsOk())
+	assert.Equal(t, "x02", response.Errors.Items[0].Code)
+}
+
+func TestServerSimulateMalformedJson(t *testing.T) {
+	server := NewServer(t)
+	defer server.Close()
+	server.On("GET", "/users").Malformed()
+
+	apicall := pkg.NewApiCall(pkg.WithBaseUrl(server.URL))
+	response, _ := apicall.Send("GET", "/users", nil)
+
+	assert.False(t, response.IsOk())
+}
+
+func TestServerSimulateTimeoutAndLatency(t *testing.T) {
+	server := NewServer(t)
+	defer server.Close()
+	server.On("GET", "/slow").Items([]user{{Name: "Jonathan"}}).Latency(20 * time.Millisecond)
+	server.On("GET", "/hang").Timeout()
+
+	apicall := pkg.NewApiCall(pkg.WithBaseUrl(server.URL))
+	start := time.Now()
+	slow, _ := apicall.Send("GET", "/slow", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	hang, _ := apicall.SendWithContext(ctx, "GET", "/hang", nil)
+
+	assert.True(t, slow.IsOk())
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+	assert.False(t, hang.IsOk())
+	assert.Equal(t, "1", hang.Errors.Items[0].Code)
+}
+
+func TestServerAssertCallsAndOrder(t *testing.T) {
+	server := NewServer(t)
+	defer server.Close()
+	server.On("POST", "/login").Times(1)
+	server.On("GET", "/users").Times(2)
+
+	apicall := pkg.NewApiCall(pkg.WithBaseUrl(server.URL))
+	_, _ = apicall.Send("POST", "/login", nil)
+	_, _ = apicall.Send("GET", "/users", nil)
+	_, _ = apicall.Send("GET", "/users", nil)
+
+	assert.True(t, server.AssertCalled(t, "GET", "/users", 2))
+	assert.True(t, server.AssertOrder(t, "POST /login", "GET /users", "GET /users"))
+	assert.Len(t, server.Calls(), 3)
+}
+
+func TestServerFailOnUnexpectedRequest(t *testing.T) {
+	server := &Server{t: t}
+	server.On("GET", "/users").WithHeader("Authorization", "Bearer a")
+
+	route, mismatch := server.match(Call{Method: "GET", Path: "/users", Header: map[string][]string{"Authorization": {"Bearer b"}}})
+
+	assert.Nil(t, route)
+	assert.Contains(t, mismatch, `header Authorization is ["Bearer b"], expected ["Bearer a"]`)
+}
@@ -0,0 +1,251 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Strategy choose endpoint of each request among healthy
+// endpoints, it is made by RoundRobin, Random, LeastOutstanding
+// or Weighted
+type Strategy interface {
+	choose(endpoints []*endpoint) *endpoint
+}
+
+// Ejection configure when an endpoint stop receiving requests
+type Ejection struct {
+	// Failures is how many consecutive failures eject an endpoint, a failure is a
+	// connection error or a 5xx response, when zero 5 is used and negative never eject
+	Failures int
+	// Duration is how long endpoint is ejected, when zero 30 seconds is used
+	Duration time.Duration
+}
+
+var defaultEjection = Ejection{Failures: 5, Duration: 30 * time.Second}
+
+type balancer struct {
+	endpoints []*endpoint
+	strategy  Strategy
+	mu        sync.Mutex
+}
+
+type endpoint struct {
+	index          int
+	url            string
+	outstanding    int64
+	failures       int
+	ejectedUntil   time.Time
+	unhealthy      bool
+	probeSuccesses int
+	probeFailures  int
+	probeError     string
+}
+
+// WithBaseUrls it will send each request to one of urls chosen by strategy,
+// RoundRobin when nil, an endpoint which keep failing is ejected
+// for a while, see WithEjection
+func WithBaseUrls(urls []string, strategy Strategy) Option {
+	return func(a ApiCall) *ApiCall {
+		if len(urls) == 0 {
+			a.addError(errors.New("base urls: at least one url is required"))
+			return &a
+		}
+		for _, url := range urls {
+			if err := validateBaseUrl(url); err != nil && a.strict {
+				a.addError(err)
+				return &a
+			}
+		}
+		if strategy == nil {
+			strategy = RoundRobin()
+		}
+		b := &balancer{strategy: strategy}
+		for index, url := range urls {
+			b.endpoints = append(b.endpoints, &endpoint{index: index, url: url})
+		}
+		a.BaseUrl = urls[0]
+		a.balancer = b
+		return &a
+	}
+}
+
+// WithEjection it will change when endpoints of WithBaseUrls are ejected
+func WithEjection(ejection Ejection) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && ejection.Duration < 0 {
+			a.addError(fmt.Errorf("ejection: duration must not be negative, got %v", ejection.Duration))
+			return &a
+		}
+		a.ejection = ejection
+		return &a
+	}
+}
+
+// pick return endpoint of next request, when every endpoint is
+// ejected or unhealthy it choose among all of them, nil when there isn't a balancer
+func (b *balancer) pick() *endpoint {
+	if b == nil {
+		return nil
+	}
+	now := time.Now()
+	b.mu.Lock()
+	var healthy []*endpoint
+	for _, e := range b.endpoints {
+		if !e.unhealthy && !now.Before(e.ejectedUntil) {
+			healthy = append(healthy, e)
+		}
+	}
+	b.mu.Unlock()
+	if len(healthy) == 0 {
+		healthy = b.endpoints
+	}
+
+	e := b.strategy.choose(healthy)
+	atomic.AddInt64(&e.outstanding, 1)
+	return e
+}
+
+// done it will count result of request sent to e,
+// ejecting it after too many consecutive failures
+func (b *balancer) done(e *endpoint, failed bool, ejection Ejection) {
+	if e == nil {
+		return
+	}
+	b.release(e)
+	if ejection.Failures == 0 {
+		ejection.Failures = defaultEjection.Failures
+	}
+	if ejection.Duration == 0 {
+		ejection.Duration = defaultEjection.Duration
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		e.failures = 0
+		return
+	}
+	e.failures++
+	if ejection.Failures > 0 && e.failures >= ejection.Failures {
+		e.failures = 0
+		e.ejectedUntil = time.Now().Add(ejection.Duration)
+	}
+}
+
+// status return status of e at now, balancer must be locked
+func (e *endpoint) status(now time.Time) EndpointStatus {
+	return EndpointStatus{
+		Url:     e.url,
+		Healthy: !e.unhealthy,
+		Ejected: now.Before(e.ejectedUntil),
+		Error:   e.probeError,
+	}
+}
+
+// release it will end request sent to e without counting its result
+func (b *balancer) release(e *endpoint) {
+	if e != nil {
+		atomic.AddInt64(&e.outstanding, -1)
+	}
+}
+
+// endpointCanceled return true when request was canceled by its caller,
+// which tell nothing about endpoint, unlike a timeout
+func endpointCanceled(ctx context.Context, err error) bool {
+	return err != nil && (errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled)
+}
+
+// endpointFailed return true when request to an endpoint failed
+func endpointFailed(response *http.Response, err error) bool {
+	return err != nil || response.StatusCode >= http.StatusInternalServerError
+}
+
+type roundRobin struct {
+	next uint32
+}
+
+// RoundRobin choose endpoints one after another
+func RoundRobin() Strategy {
+	return &roundRobin{}
+}
+
+func (r *roundRobin) choose(endpoints []*endpoint) *endpoint {
+	return endpoints[int(atomic.AddUint32(&r.next, 1)-1)%len(endpoints)]
+}
+
+type random struct{}
+
+// Random choose any endpoint
+func Random() Strategy {
+	return random{}
+}
+
+func (random) choose(endpoints []*endpoint) *endpoint {
+	return endpoints[rand.Intn(len(endpoints))]
+}
+
+type leastOutstanding struct {
+	next uint32
+}
+
+// LeastOutstanding choose endpoint with fewer requests in flight,
+// endpoints with same number are chosen one after another
+func LeastOutstanding() Strategy {
+	return &leastOutstanding{}
+}
+
+func (l *leastOutstanding) choose(endpoints []*endpoint) *endpoint {
+	start := int(atomic.AddUint32(&l.next, 1)-1) % len(endpoints)
+	chosen := endpoints[start]
+	for i := 1; i < len(endpoints); i++ {
+		e := endpoints[(start+i)%len(endpoints)]
+		if atomic.LoadInt64(&e.outstanding) < atomic.LoadInt64(&chosen.outstanding) {
+			chosen = e
+		}
+	}
+	return chosen
+}
+
+type weighted struct {
+	weights []int
+	mu      sync.Mutex
+	current map[int]int
+}
+
+// Weighted choose endpoints in proportion of their weight, given by
+// order of urls, endpoints without weight have weight 1
+func Weighted(weights ...int) Strategy {
+	return &weighted{weights: weights, current: make(map[int]int)}
+}
+
+// choose it will use smooth weighted round robin, so
+// endpoints with large weights don't receive bursts
+func (w *weighted) choose(endpoints []*endpoint) *endpoint {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var chosen *endpoint
+	total := 0
+	for _, e := range endpoints {
+		weight := w.weight(e)
+		total += weight
+		w.current[e.index] += weight
+		if chosen == nil || w.current[e.index] > w.current[chosen.index] {
+			chosen = e
+		}
+	}
+	w.current[chosen.index] -= total
+	return chosen
+}
+
+func (w *weighted) weight(e *endpoint) int {
+	if e.index < len(w.weights) && w.weights[e.index] > 0 {
+		return w.weights[e.index]
+	}
+	return 1
+}
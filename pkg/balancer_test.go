@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func sendEndpoints(apicall *ApiCall, n int) []string {
+	var endpoints []string
+	for i := 0; i < n; i++ {
+		response, _ := apicall.Send("GET", "/", nil)
+		endpoints = append(endpoints, response.Endpoint)
+	}
+	return endpoints
+}
+
+func TestRoundRobinBaseUrls(t *testing.T) {
+	a := newNamedServer("a")
+	defer a.Close()
+	b := newNamedServer("b")
+	defer b.Close()
+
+	apicall := NewApiCall(WithBaseUrls([]string{a.URL, b.URL}, RoundRobin()))
+
+	assert.Equal(t, []string{a.URL, b.URL, a.URL}, sendEndpoints(apicall, 3))
+}
+
+func TestWeightedBaseUrls(t *testing.T) {
+	a := newNamedServer("a")
+	defer a.Close()
+	b := newNamedServer("b")
+	defer b.Close()
+
+	apicall := NewApiCall(WithBaseUrls([]string{a.URL, b.URL}, Weighted(3, 1)))
+
+	assert.Equal(t, []string{a.URL, a.URL, b.URL, a.URL, a.URL, a.URL, b.URL, a.URL}, sendEndpoints(apicall, 8))
+}
+
+func TestPassiveEjection(t *testing.T) {
+	a := newNamedServer("a")
+	defer a.Close()
+	b := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer b.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrls([]string{a.URL, b.URL}, RoundRobin()),
+		WithEjection(Ejection{Failures: 2, Duration: time.Minute}),
+	)
+
+	assert.Equal(t, []string{a.URL, b.URL, a.URL, b.URL, a.URL, a.URL}, sendEndpoints(apicall, 6))
+}
+
+func TestCanceledRequestsDontEject(t *testing.T) {
+	received := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received <- struct{}{}
+		<-request.Context().Done()
+	}))
+	defer ts.Close()
+	apicall := NewApiCall(
+		WithBaseUrls([]string{ts.URL}, RoundRobin()),
+		WithEjection(Ejection{Failures: 1, Duration: time.Minute}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-received
+		cancel()
+	}()
+	canceled, _ := apicall.SendWithContext(ctx, "GET", "/", nil)
+	assert.False(t, canceled.IsOk())
+	assert.False(t, apicall.EndpointStatus()[0].Ejected)
+
+	go func() {
+		<-received
+	}()
+	timedOut, _ := apicall.With(WithTimeout(20*time.Millisecond)).Send("GET", "/", nil)
+	assert.False(t, timedOut.IsOk())
+	assert.True(t, apicall.EndpointStatus()[0].Ejected)
+}
+
+func TestEjectionOfEveryEndpointFallbackToAll(t *testing.T) {
+	b := &balancer{strategy: RoundRobin()}
+	for index, url := range []string{"http://a", "http://b"} {
+		b.endpoints = append(b.endpoints, &endpoint{index: index, url: url})
+	}
+	for _, e := range b.endpoints {
+		b.done(b.pick(), true, Ejection{Failures: 1})
+		assert.True(t, e.ejectedUntil.After(time.Now()))
+	}
+
+	assert.NotNil(t, b.pick())
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	endpoints := []*endpoint{{index: 0, outstanding: 3}, {index: 1, outstanding: 1}, {index: 2, outstanding: 2}}
+	strategy := LeastOutstanding()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 1, strategy.choose(endpoints).index)
+	}
+}
+
+func TestRandom(t *testing.T) {
+	endpoints := []*endpoint{{index: 0}, {index: 1}}
+	chosen := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		chosen[Random().choose(endpoints).index] = true
+	}
+
+	assert.Equal(t, map[int]bool{0: true, 1: true}, chosen)
+}
+
+func TestBaseUrlsAreRequired(t *testing.T) {
+	apicall := NewApiCall(WithBaseUrls(nil, RoundRobin()))
+	_, err := apicall.Send("GET", "/", nil)
+
+	assert.EqualError(t, err, "base urls: at least one url is required")
+}
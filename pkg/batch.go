@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Request describe a request sent by SendAll
+type Request struct {
+	Method string
+	Url    string
+	Body   io.Reader
+	// Headers replace headers of ApiCall with same key
+	Headers http.Header
+}
+
+// BatchOptions configure how SendAll send requests
+type BatchOptions struct {
+	// Parallelism is how many requests are sent at once,
+	// when zero every request is sent at once
+	Parallelism int
+	// FailFast cancel requests still pending after first failure and
+	// report only it, otherwise every request is sent and all errors are collected
+	FailFast bool
+}
+
+// BatchError is error of a single request sent by SendAll
+type BatchError struct {
+	// Index of request in slice given to SendAll
+	Index int
+	// OperationId of request response
+	OperationId string
+	Err         error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("request %d [%s]: %v", e.Index, e.OperationId, e.Err)
+}
+
+// MultiError aggregate errors of SendAll, ordered by request index
+type MultiError []BatchError
+
+func (m MultiError) Error() string {
+	s := make([]string, len(m))
+	for index, e := range m {
+		s[index] = e.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// SendAll it will send every request with bounded parallelism, responses
+// are returned in same order of requests, a request fail when Send return
+// an error, response has Errors or status code isn't 2xx
+func (a *ApiCall) SendAll(ctx context.Context, requests []Request, options BatchOptions) ([]*BaseStandard, error) {
+	parallelism := options.Parallelism
+	if parallelism <= 0 || parallelism > len(requests) {
+		parallelism = len(requests)
+	}
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var errs MultiError
+	responses := make([]*BaseStandard, len(requests))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				response, err := a.sendBatchRequest(batchCtx, requests[index])
+				responses[index] = response
+				if err == nil {
+					continue
+				}
+				mu.Lock()
+				// with fail fast only first failure is reported,
+				// others are caused by it
+				if !options.FailFast || len(errs) == 0 {
+					errs = append(errs, BatchError{Index: index, OperationId: operationIdOf(response), Err: err})
+				}
+				mu.Unlock()
+				if options.FailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for index := range requests {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return responses, nil
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Index < errs[j].Index
+	})
+	return responses, errs
+}
+
+func (a *ApiCall) sendBatchRequest(ctx context.Context, request Request) (*BaseStandard, error) {
+	if ctx.Err() != nil {
+		return formatExceptionResponse(newBaseStandard(a), nil, ctx.Err()), ctx.Err()
+	}
+	response, err := a.SendWithHeaders(ctx, request.Method, request.Url, request.Body, request.Headers)
+	if err != nil {
+		return response, err
+	}
+	if len(response.AuditInfo.Errors.Items) > 0 {
+		return response, fmt.Errorf("%s", response.AuditInfo.Errors.String())
+	}
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return response, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	return response, nil
+}
+
+func operationIdOf(response *BaseStandard) string {
+	if response == nil {
+		return ""
+	}
+	return response.AuditInfo.OperationId
+}
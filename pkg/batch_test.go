@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendAllKeepOrder(t *testing.T) {
+	var concurrent, maxConcurrent int64
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		current := atomic.AddInt64(&concurrent, 1)
+		defer atomic.AddInt64(&concurrent, -1)
+		for {
+			max := atomic.LoadInt64(&maxConcurrent)
+			if current <= max || atomic.CompareAndSwapInt64(&maxConcurrent, max, current) {
+				break
+			}
+		}
+		delay, _ := strconv.Atoi(request.URL.Query().Get("delay"))
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[{"delay":` + request.URL.Query().Get("delay") + `}]}`))
+	}))
+	defer ts.Close()
+	requests := []Request{
+		{Method: "GET", Url: "/?delay=40"},
+		{Method: "GET", Url: "/?delay=30"},
+		{Method: "GET", Url: "/?delay=20"},
+		{Method: "GET", Url: "/?delay=10"},
+	}
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	responses, err := apicall.SendAll(context.Background(), requests, BatchOptions{Parallelism: 2})
+
+	assert.Nil(t, err)
+	assert.Len(t, responses, 4)
+	for index, delay := range []string{"40", "30", "20", "10"} {
+		assert.Equal(t, `[{"delay":`+delay+`}]`, string(*responses[index].Items))
+	}
+	assert.Equal(t, int64(2), atomic.LoadInt64(&maxConcurrent))
+}
+
+func TestSendAllCollectAllErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		if request.URL.Path == "/fail" {
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = writer.Write([]byte(`{"items":[{"echo":"Hello World"}]}`))
+	}))
+	defer ts.Close()
+	requests := []Request{
+		{Method: "GET", Url: "/fail"},
+		{Method: "GET", Url: "/"},
+		{Method: "GET", Url: "/fail"},
+	}
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	responses, err := apicall.SendAll(context.Background(), requests, BatchOptions{})
+
+	assert.Len(t, responses, 3)
+	assert.True(t, responses[1].IsOk())
+	errs, ok := err.(MultiError)
+	assert.True(t, ok)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, 0, errs[0].Index)
+	assert.Equal(t, responses[0].OperationId, errs[0].OperationId)
+	assert.Equal(t, 2, errs[1].Index)
+	assert.EqualError(t, errs[1].Err, "unexpected status code 500")
+	assert.Equal(t, "request 0 ["+responses[0].OperationId+"]: unexpected status code 500; request 2 ["+responses[2].OperationId+"]: unexpected status code 500", err.Error())
+}
+
+func TestSendAllFailFast(t *testing.T) {
+	var calls int64
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadGateway)
+		_, _ = writer.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+	requests := []Request{
+		{Method: "GET", Url: "/"},
+		{Method: "GET", Url: "/"},
+		{Method: "GET", Url: "/"},
+	}
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	responses, err := apicall.SendAll(context.Background(), requests, BatchOptions{Parallelism: 1, FailFast: true})
+
+	errs, ok := err.(MultiError)
+	assert.True(t, ok)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 0, errs[0].Index)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+	assert.Len(t, responses, 3)
+	assert.Equal(t, "2", responses[2].AuditInfo.Errors.Items[0].Code)
+	assert.Equal(t, "Canceled", responses[2].AuditInfo.Errors.Items[0].Description)
+}
+
+func TestSendAllWithoutRequests(t *testing.T) {
+	apicall := NewApiCall()
+	responses, err := apicall.SendAll(context.Background(), nil, BatchOptions{})
+
+	assert.Nil(t, err)
+	assert.Empty(t, responses)
+}
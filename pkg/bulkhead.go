@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// errQueueFull is returned when bulkhead queue can't hold another request
+var errQueueFull = errors.New("queue is full")
+
+type bulkhead struct {
+	slots    chan struct{}
+	maxQueue int
+	queued   int64
+}
+
+// WithMaxConcurrency it will allow at most n requests in flight,
+// other requests wait until a slot is free or context is done
+func WithMaxConcurrency(n int) Option {
+	return WithBulkhead(n, -1)
+}
+
+// WithBulkhead it will allow at most n requests in flight and
+// maxQueue requests waiting, when queue is full request is rejected
+// immediately with a "Queue full" error, a negative maxQueue is unlimited
+func WithBulkhead(n, maxQueue int) Option {
+	return func(a ApiCall) *ApiCall {
+		if n < 1 {
+			if a.strict {
+				a.addError(fmt.Errorf("concurrency: must be at least 1, got %d", n))
+				return &a
+			}
+			n = 1
+		}
+		a.bulkhead = &bulkhead{slots: make(chan struct{}, n), maxQueue: maxQueue}
+		return &a
+	}
+}
+
+// InFlight return how many requests are being sent,
+// it is only tracked when a concurrency limit is set
+func (a *ApiCall) InFlight() int {
+	if a.bulkhead == nil {
+		return 0
+	}
+	return len(a.bulkhead.slots)
+}
+
+// QueueDepth return how many requests are waiting for a slot,
+// it is only tracked when a concurrency limit is set
+func (a *ApiCall) QueueDepth() int {
+	if a.bulkhead == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&a.bulkhead.queued))
+}
+
+// acquire it will block until a slot is free, ctx is done or
+// return immediately when queue is full
+func (b *bulkhead) acquire(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	queued := atomic.AddInt64(&b.queued, 1)
+	defer atomic.AddInt64(&b.queued, -1)
+	if b.maxQueue >= 0 && queued > int64(b.maxQueue) {
+		return errQueueFull
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *bulkhead) release() {
+	if b == nil {
+		return
+	}
+	<-b.slots
+}
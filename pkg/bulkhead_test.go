@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newBlockingServer(release chan struct{}, concurrent, maxConcurrent *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		current := atomic.AddInt64(concurrent, 1)
+		for {
+			max := atomic.LoadInt64(maxConcurrent)
+			if current <= max || atomic.CompareAndSwapInt64(maxConcurrent, max, current) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt64(concurrent, -1)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[{"echo":"Hello World"}]}`))
+	}))
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	var concurrent, maxConcurrent int64
+	release := make(chan struct{})
+	ts := newBlockingServer(release, &concurrent, &maxConcurrent)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithMaxConcurrency(2),
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := apicall.Send("GET", "/", nil)
+			assert.Nil(t, err)
+			assert.True(t, response.IsOk())
+		}()
+	}
+
+	assert.Eventually(t, func() bool {
+		return apicall.InFlight() == 2 && apicall.QueueDepth() == 2
+	}, time.Second, 5*time.Millisecond)
+	// slots are taken before requests reach server, so wait for them to arrive
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&concurrent) == 2
+	}, time.Second, 5*time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&maxConcurrent))
+	assert.Equal(t, 0, apicall.InFlight())
+	assert.Equal(t, 0, apicall.QueueDepth())
+}
+
+func TestBulkheadRejectWhenQueueIsFull(t *testing.T) {
+	var concurrent, maxConcurrent int64
+	release := make(chan struct{})
+	ts := newBlockingServer(release, &concurrent, &maxConcurrent)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithBulkhead(1, 0),
+	)
+	done := make(chan struct{})
+	go func() {
+		_, _ = apicall.Send("GET", "/", nil)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return apicall.InFlight() == 1
+	}, time.Second, 5*time.Millisecond)
+
+	response, err := apicall.Send("GET", "/", nil)
+	close(release)
+	<-done
+
+	assert.Nil(t, err)
+	assert.False(t, response.IsOk())
+	assert.Equal(t, "3", response.AuditInfo.Errors.Items[0].Code)
+	assert.Equal(t, "Queue full", response.AuditInfo.Errors.Items[0].Description)
+}
+
+func TestBulkheadQueueRespectTimeout(t *testing.T) {
+	var concurrent, maxConcurrent int64
+	release := make(chan struct{})
+	ts := newBlockingServer(release, &concurrent, &maxConcurrent)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithMaxConcurrency(1),
+	)
+	done := make(chan struct{})
+	go func() {
+		_, _ = apicall.Send("GET", "/", nil)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return apicall.InFlight() == 1
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	response, err := apicall.SendWithContext(ctx, "GET", "/", nil)
+	close(release)
+	<-done
+
+	assert.Nil(t, err)
+	assert.Equal(t, "1", response.AuditInfo.Errors.Items[0].Code)
+	assert.Equal(t, 0, apicall.QueueDepth())
+}
+
+func TestGaugesWithoutConcurrencyLimit(t *testing.T) {
+	apicall := NewApiCall()
+
+	assert.Equal(t, 0, apicall.InFlight())
+	assert.Equal(t, 0, apicall.QueueDepth())
+}
@@ -0,0 +1,221 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Codec marshal and unmarshal values of a media type,
+// e.g. a MessagePack or CBOR implementation can be
+// plugged with RegisterCodec
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSONCodec marshal and unmarshal using encoding/json
+	JSONCodec Codec = jsonCodec{}
+	// XMLCodec marshal and unmarshal using encoding/xml, response
+	// items are kept apart as raw xml of <items> element, so
+	// BaseStandard.Items is nil and they are read with GetItems
+	XMLCodec Codec = xmlCodec{}
+)
+
+var codecs = struct {
+	sync.RWMutex
+	byMediaType map[string]Codec
+}{
+	byMediaType: map[string]Codec{
+		"application/json": JSONCodec,
+		"application/xml":  XMLCodec,
+		"text/xml":         XMLCodec,
+	},
+}
+
+// RegisterCodec it will use codec for request bodies and
+// responses of mediaType, e.g. application/msgpack
+func RegisterCodec(mediaType string, codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.byMediaType[mediaType] = codec
+}
+
+// WithContentType it will modified ApiCall.ContentType field
+func WithContentType(mediaType string) Option {
+	return func(a ApiCall) *ApiCall {
+		if _, _, err := mime.ParseMediaType(mediaType); err != nil && a.strict {
+			a.addError(fmt.Errorf("content type: %w", err))
+			return &a
+		}
+		a.ContentType = mediaType
+		return &a
+	}
+}
+
+// WithAccept it will modified ApiCall.Accept field,
+// media types are sent by order of preference
+func WithAccept(mediaTypes ...string) Option {
+	return func(a ApiCall) *ApiCall {
+		for _, mediaType := range mediaTypes {
+			if _, _, err := mime.ParseMediaType(mediaType); err != nil && a.strict {
+				a.addError(fmt.Errorf("accept: %w", err))
+				return &a
+			}
+		}
+		a.Accept = mediaTypes
+		return &a
+	}
+}
+
+// codecFor return codec registered for contentType, structured
+// syntax suffixes like +json and +xml are also supported
+func codecFor(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+	if codec, ok := codecs.byMediaType[mediaType]; ok {
+		return codec, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSONCodec, true
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XMLCodec, true
+	}
+	return nil, false
+}
+
+// acceptHeader it will format media types into Accept header,
+// each one with a lower quality than previous one
+func acceptHeader(mediaTypes []string) string {
+	accept := make([]string, len(mediaTypes))
+	for index, mediaType := range mediaTypes {
+		quality := 10 - index
+		if quality < 1 {
+			quality = 1
+		}
+		accept[index] = mediaType
+		if index > 0 {
+			accept[index] = fmt.Sprintf("%s;q=0.%d", mediaType, quality)
+		}
+	}
+	return strings.Join(accept, ", ")
+}
+
+// decodeWithCodec it will decode a response which isn't json into
+// baseResponse, codecs other than XMLCodec must be able to unmarshal
+// into an interface{}, which is converted into json
+func decodeWithCodec(a *ApiCall, codec Codec, raw []byte, baseResponse *BaseStandard) error {
+	if _, ok := codec.(xmlCodec); ok {
+		return decodeXML(raw, baseResponse)
+	}
+
+	var document interface{}
+	if err := codec.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+	binary, err := json.Marshal(normalize(document))
+	if err != nil {
+		return err
+	}
+	decodeEnvelope(a, envelopeFor(a, ""), binary, baseResponse)
+	return nil
+}
+
+// normalize it will convert map[interface{}]interface{}, used
+// by some codecs, into map[string]interface{} which json support
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalize(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+	case []interface{}:
+		for index, item := range v {
+			v[index] = normalize(item)
+		}
+	}
+	return value
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// decodeXML it will decode a xml BaseStandard, e.g.
+// <response><items>...</items><auditInfo>...</auditInfo></response>
+func decodeXML(raw []byte, baseResponse *BaseStandard) error {
+	var document struct {
+		Items *struct {
+			Raw []byte `xml:",innerxml"`
+		} `xml:"items"`
+		AuditInfo *AuditInfo `xml:"auditInfo"`
+	}
+	document.AuditInfo = &baseResponse.AuditInfo
+	if err := xml.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+
+	baseResponse.codec = XMLCodec
+	if document.Items != nil {
+		baseResponse.rawItems = []byte("<items>" + string(document.Items.Raw) + "</items>")
+	}
+	return nil
+}
+
+// hasXMLItems return true if <items> element has any child element or text
+func hasXMLItems(raw []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 1 {
+				return true
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 1 && len(bytes.TrimSpace(t)) > 0 {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package pkg
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// interfaceKeysCodec is json on wire but decode objects
+// into map[interface{}]interface{} like some msgpack codecs
+type interfaceKeysCodec struct{}
+
+func (interfaceKeysCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (interfaceKeysCodec) Unmarshal(data []byte, v interface{}) error {
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	m := make(map[interface{}]interface{})
+	for key, value := range document {
+		m[key] = value
+	}
+	*(v.(*interface{})) = m
+	return nil
+}
+
+func TestDecodeXMLResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		_, _ = writer.Write([]byte(`<response><items><user><name>Jonathan</name></user><user><name>Maria</name></user></items><auditInfo><duration>0.027</duration><host>test.com</host><ok>true</ok><total>2</total><warning><items><code>x01</code><description>Deprecated</description></items></warning></auditInfo></response>`))
+	}))
+	defer ts.Close()
+	type Users struct {
+		Users []struct {
+			Name string `xml:"name"`
+		} `xml:"user"`
+	}
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/users", nil)
+	var users Users
+	errGetItems := response.GetItems(&users)
+
+	assert.Nil(t, err)
+	assert.Nil(t, errGetItems)
+	assert.True(t, response.IsOk())
+	assert.Len(t, users.Users, 2)
+	assert.Equal(t, "Maria", users.Users[1].Name)
+	assert.Equal(t, 0.027, response.AuditInfo.Duration)
+	assert.Equal(t, "test.com", response.AuditInfo.Host)
+	assert.Equal(t, int64(2), response.AuditInfo.Total)
+	assert.Equal(t, "[x01]: Deprecated", response.AuditInfo.Warning.String())
+}
+
+func TestXMLResponseCanBeMarshalledToJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/xml")
+		_, _ = writer.Write([]byte(`<response><items><user><name>Jonathan</name></user></items><auditInfo><host>test.com</host></auditInfo></response>`))
+	}))
+	defer ts.Close()
+
+	response, err := NewApiCall(WithBaseUrl(ts.URL)).Send("GET", "/users", nil)
+	binary, errMarshal := json.Marshal(response)
+
+	assert.Nil(t, err)
+	assert.Nil(t, errMarshal)
+	assert.True(t, response.IsOk())
+	assert.Nil(t, response.Items)
+	assert.Contains(t, string(binary), `"items":null`)
+	assert.Contains(t, string(binary), `"host":"test.com"`)
+}
+
+func TestDecodeXMLWithoutItems(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/xml")
+		_, _ = writer.Write([]byte(`<response><items> </items></response>`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/users", nil)
+
+	assert.Nil(t, err)
+	assert.False(t, response.HasItems())
+	assert.Empty(t, response.AuditInfo.Errors.Items)
+}
+
+func TestDecodeWithRegisteredCodec(t *testing.T) {
+	RegisterCodec("application/x-interface-keys", interfaceKeysCodec{})
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/x-interface-keys")
+		_, _ = writer.Write([]byte(`{"items":[{"echo":"Hello World"}],"auditInfo":{"host":"test.com"}}`))
+	}))
+	defer ts.Close()
+	type MyItems struct {
+		Echo string `json:"echo"`
+	}
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+	var items []MyItems
+	errGetItems := response.GetItems(&items)
+
+	assert.Nil(t, err)
+	assert.Nil(t, errGetItems)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, []MyItems{{"Hello World"}}, items)
+	assert.Equal(t, "test.com", response.AuditInfo.Host)
+}
+
+func TestSendValueWithXMLContentType(t *testing.T) {
+	var contentType, accept string
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		contentType = request.Header.Get("Content-Type")
+		accept = request.Header.Get("Accept")
+		body, _ = ioutil.ReadAll(request.Body)
+		writer.Header().Set("Content-Type", "application/xml")
+		_, _ = writer.Write([]byte(`<response><items><ok/></items></response>`))
+	}))
+	defer ts.Close()
+	type User struct {
+		XMLName xml.Name `xml:"user"`
+		Name    string   `xml:"name"`
+	}
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithContentType("application/xml"),
+		WithAccept("application/xml", "application/json"),
+	)
+	response, err := apicall.SendValue("POST", "/users", User{Name: "Jonathan"})
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, "application/xml", contentType)
+	assert.Equal(t, "application/xml, application/json;q=0.9", accept)
+	assert.Equal(t, "<user><name>Jonathan</name></user>", string(body))
+}
+
+func TestSendValueWithoutCodec(t *testing.T) {
+	apicall := NewApiCall(
+		WithContentType("application/x-unknown"),
+	)
+	response, err := apicall.SendValue("POST", "/users", nil)
+
+	assert.Nil(t, response)
+	assert.EqualError(t, err, "no codec registered for application/x-unknown")
+}
+
+func TestSendDoesNotChangeHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[1]}`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithAccept("application/json"),
+	)
+	_, _ = apicall.Send("GET", "/", nil)
+	_, _ = apicall.Send("GET", "/", nil)
+
+	assert.Empty(t, apicall.Headers)
+}
+
+func TestAcceptHeader(t *testing.T) {
+	mediaTypes := []string{"a/1", "a/2", "a/3", "a/4", "a/5", "a/6", "a/7", "a/8", "a/9", "a/10", "a/11"}
+
+	assert.Equal(t, "a/1", acceptHeader(mediaTypes[:1]))
+	assert.Equal(t, "a/1, a/2;q=0.9, a/3;q=0.8, a/4;q=0.7, a/5;q=0.6, a/6;q=0.5, a/7;q=0.4, a/8;q=0.3, a/9;q=0.2, a/10;q=0.1, a/11;q=0.1", acceptHeader(mediaTypes))
+}
+
+func TestCodecFor(t *testing.T) {
+	tables := []struct {
+		contentType string
+		codec       Codec
+		ok          bool
+	}{
+		{"application/json; charset=UTF-8", JSONCodec, true},
+		{"application/problem+json", JSONCodec, true},
+		{"application/atom+xml", XMLCodec, true},
+		{"text/html", nil, false},
+		{"", nil, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.contentType, func(t *testing.T) {
+			codec, ok := codecFor(table.contentType)
+			assert.Equal(t, table.codec, codec)
+			assert.Equal(t, table.ok, ok)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Compressor return a writer which compress into w
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// Decompressor return a reader which decompress r,
+// e.g. a brotli implementation can be plugged with RegisterDecompressor
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var compressors = struct {
+	sync.RWMutex
+	byEncoding map[string]Compressor
+}{
+	byEncoding: map[string]Compressor{
+		"gzip": func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		"deflate": func(w io.Writer) (io.WriteCloser, error) {
+			return zlib.NewWriter(w), nil
+		},
+	},
+}
+
+var decompressors = struct {
+	sync.RWMutex
+	byEncoding map[string]Decompressor
+}{
+	byEncoding: map[string]Decompressor{
+		"gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		"deflate": inflate,
+	},
+}
+
+// RegisterCompressor it will use compressor for request bodies
+// when ApiCall.Compression is encoding
+func RegisterCompressor(encoding string, compressor Compressor) {
+	compressors.Lock()
+	defer compressors.Unlock()
+	compressors.byEncoding[encoding] = compressor
+}
+
+// RegisterDecompressor it will use decompressor for responses
+// with encoding as Content-Encoding, e.g. br
+func RegisterDecompressor(encoding string, decompressor Decompressor) {
+	decompressors.Lock()
+	defer decompressors.Unlock()
+	decompressors.byEncoding[encoding] = decompressor
+}
+
+// WithCompression it will compress request bodies with encoding,
+// only when body has at least threshold bytes
+func WithCompression(encoding string, threshold int) Option {
+	return func(a ApiCall) *ApiCall {
+		compressors.RLock()
+		_, ok := compressors.byEncoding[encoding]
+		compressors.RUnlock()
+		if a.strict && !ok {
+			a.addError(fmt.Errorf("compression: no compressor registered for %s", encoding))
+			return &a
+		}
+		if a.strict && threshold < 0 {
+			a.addError(fmt.Errorf("compression: threshold must not be negative, got %d", threshold))
+			return &a
+		}
+		a.Compression = encoding
+		a.CompressionThreshold = threshold
+		return &a
+	}
+}
+
+// WithDecompression it will accept responses compressed with any
+// registered decompressor and decompress them
+func WithDecompression() Option {
+	return func(a ApiCall) *ApiCall {
+		a.Decompression = true
+		return &a
+	}
+}
+
+// acceptEncoding return every registered encoding
+// which can be decompressed
+func acceptEncoding() string {
+	decompressors.RLock()
+	defer decompressors.RUnlock()
+	encodings := make([]string, 0, len(decompressors.byEncoding))
+	for encoding := range decompressors.byEncoding {
+		encodings = append(encodings, encoding)
+	}
+	sort.Strings(encodings)
+	return strings.Join(encodings, ", ")
+}
+
+// compressRequest it will compress body when ApiCall.Compression is set
+// and body reach threshold, Content-Encoding is added into headers
+func compressRequest(a *ApiCall, baseResponse *BaseStandard, body io.Reader, headers http.Header) (io.Reader, error) {
+	if a.Compression == "" || body == nil {
+		return body, nil
+	}
+	compressors.RLock()
+	compressor, ok := compressors.byEncoding[a.Compression]
+	compressors.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no compressor registered for %s", a.Compression)
+	}
+
+	binary, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	baseResponse.AuditInfo.RequestSize = int64(len(binary))
+	if len(binary) < a.CompressionThreshold {
+		return bytes.NewReader(binary), nil
+	}
+
+	var compressed bytes.Buffer
+	writer, err := compressor(&compressed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(binary); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	headers.Set("Content-Encoding", a.Compression)
+	baseResponse.AuditInfo.RequestCompressedSize = int64(compressed.Len())
+	return &compressed, nil
+}
+
+// readResponse it will read whole response body, decompressing it
+// when ApiCall.Decompression is set, compressed size is returned
+// only when response was decompressed
+func readResponse(a *ApiCall, response *http.Response) ([]byte, int64, error) {
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+	if !a.Decompression || encoding == "" || encoding == "identity" {
+		binary, err := ioutil.ReadAll(response.Body)
+		return binary, 0, err
+	}
+	decompressors.RLock()
+	decompressor, ok := decompressors.byEncoding[encoding]
+	decompressors.RUnlock()
+	if !ok {
+		binary, err := ioutil.ReadAll(response.Body)
+		return binary, 0, err
+	}
+
+	counter := &countingReader{reader: response.Body}
+	reader, err := decompressor(counter)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer reader.Close()
+	binary, err := ioutil.ReadAll(reader)
+	return binary, counter.n, err
+}
+
+// inflate support both zlib wrapped deflate, as specified by http,
+// and raw deflate sent by some servers
+func inflate(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
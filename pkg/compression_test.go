@@ -0,0 +1,167 @@
+package pkg
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var compressionItems = `{"items":[` + strings.Repeat(`{"echo":"Hello World"},`, 50) + `{"echo":"Hello World"}]}`
+
+func TestCompressRequestBody(t *testing.T) {
+	var encoding, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		encoding = request.Header.Get("Content-Encoding")
+		reader, err := gzip.NewReader(request.Body)
+		assert.Nil(t, err)
+		binary, _ := ioutil.ReadAll(reader)
+		body = string(binary)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(compressionItems))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithCompression("gzip", 10),
+	)
+	response, err := apicall.Send("POST", "/", strings.NewReader(compressionItems))
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, "gzip", encoding)
+	assert.Equal(t, compressionItems, body)
+	assert.Equal(t, int64(len(compressionItems)), response.AuditInfo.RequestSize)
+	assert.NotZero(t, response.AuditInfo.RequestCompressedSize)
+	assert.True(t, response.AuditInfo.RequestCompressedSize < response.AuditInfo.RequestSize)
+}
+
+func TestCompressRequestBodyBelowThreshold(t *testing.T) {
+	var encoding, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		encoding = request.Header.Get("Content-Encoding")
+		binary, _ := ioutil.ReadAll(request.Body)
+		body = string(binary)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(compressionItems))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithCompression("deflate", 1024),
+	)
+	response, err := apicall.Send("POST", "/", strings.NewReader(`{"hello":"world"}`))
+
+	assert.Nil(t, err)
+	assert.Empty(t, encoding)
+	assert.Equal(t, `{"hello":"world"}`, body)
+	assert.Equal(t, int64(17), response.AuditInfo.RequestSize)
+	assert.Zero(t, response.AuditInfo.RequestCompressedSize)
+}
+
+func TestCompressRequestWithUnknownEncoding(t *testing.T) {
+	apicall := NewApiCall(
+		WithCompression("zstd", 0),
+	)
+	response, err := apicall.Send("POST", "/", strings.NewReader(`{}`))
+
+	assert.Nil(t, response)
+	assert.EqualError(t, err, "no compressor registered for zstd")
+}
+
+func TestDecompressResponse(t *testing.T) {
+	tables := []struct {
+		encoding string
+		compress func(w io.Writer) io.WriteCloser
+	}{
+		{"gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{"deflate", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+		{"deflate", func(w io.Writer) io.WriteCloser {
+			writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return writer
+		}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.encoding, func(t *testing.T) {
+			var acceptEncoding string
+			var compressed bytes.Buffer
+			writer := table.compress(&compressed)
+			_, _ = writer.Write([]byte(compressionItems))
+			_ = writer.Close()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+				acceptEncoding = request.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", table.encoding)
+				_, _ = w.Write(compressed.Bytes())
+			}))
+			defer ts.Close()
+
+			apicall := NewApiCall(
+				WithBaseUrl(ts.URL),
+				WithDecompression(),
+			)
+			response, err := apicall.Send("GET", "/", nil)
+
+			assert.Nil(t, err)
+			assert.True(t, response.IsOk())
+			assert.Contains(t, acceptEncoding, "gzip")
+			assert.Contains(t, acceptEncoding, "deflate")
+			assert.Equal(t, int64(len(compressionItems)), response.AuditInfo.ResponseSize)
+			assert.Equal(t, int64(compressed.Len()), response.AuditInfo.ResponseCompressedSize)
+		})
+	}
+}
+
+func TestDecompressResponseWithRegisteredDecompressor(t *testing.T) {
+	RegisterDecompressor("x-base64", func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+	})
+	encoded := base64.StdEncoding.EncodeToString([]byte(compressionItems))
+	var acceptEncoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		acceptEncoding = request.Header.Get("Accept-Encoding")
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Content-Encoding", "x-base64")
+		_, _ = writer.Write([]byte(encoded))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithDecompression(),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Contains(t, acceptEncoding, "x-base64")
+	assert.Equal(t, int64(len(encoded)), response.AuditInfo.ResponseCompressedSize)
+}
+
+func TestResponseWithoutDecompression(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(compressionItems))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(compressionItems)), response.AuditInfo.ResponseSize)
+	assert.Zero(t, response.AuditInfo.ResponseCompressedSize)
+}
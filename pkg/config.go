@@ -0,0 +1,355 @@
+package pkg
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is configuration of an ApiCall, loaded by FromConfig,
+// string values can reference a secret as ${env:NAME} or ${file:/path}
+type Config struct {
+	BaseUrl string `json:"baseUrl"`
+	// Timeout is a duration, e.g. 5s or 1m30s
+	Timeout string            `json:"timeout"`
+	Headers map[string]string `json:"headers"`
+	// Username and Password are used for basic authentication
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Token is sent as bearer authentication
+	Token string       `json:"token"`
+	Retry *RetryConfig `json:"retry"`
+	TLS   *TLSConfig   `json:"tls"`
+}
+
+// RetryConfig is retry configuration of Config, see RetryPolicy
+type RetryConfig struct {
+	MaxRetries int `json:"maxRetries"`
+	// Backoff and MaxBackoff are durations, e.g. 100ms
+	Backoff    string `json:"backoff"`
+	MaxBackoff string `json:"maxBackoff"`
+}
+
+// TLSConfig is tls configuration of Config
+type TLSConfig struct {
+	CACert     []string `json:"caCert"`
+	ClientCert string   `json:"clientCert"`
+	ClientKey  string   `json:"clientKey"`
+	// MinVersion is 1.0, 1.1, 1.2 or 1.3
+	MinVersion string   `json:"minVersion"`
+	Pins       []string `json:"pins"`
+}
+
+// configFile is a Config of a single client, or
+// many named clients under clients key
+type configFile struct {
+	Config
+	Clients map[string]Config `json:"clients"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// FromConfig it will create ApiCall of client name from file at path, which
+// is json, yaml or toml by its extension, then environment variables
+// APICALL_<NAME>_* override it, or APICALL_* when name is empty, e.g.
+// APICALL_USERS_BASE_URL, when path is empty only environment is used,
+// options are applied after configuration
+func FromConfig(path, name string, options ...Option) (*ApiCall, error) {
+	var config Config
+	if path != "" {
+		var err error
+		config, err = loadConfigFile(path, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	prefix := "APICALL_"
+	if name != "" {
+		prefix += strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name)) + "_"
+	}
+	if err := configFromEnv(&config, prefix, os.Environ()); err != nil {
+		return nil, err
+	}
+
+	configOptions, err := config.options()
+	if err != nil {
+		return nil, err
+	}
+	return New(append(configOptions, options...)...)
+}
+
+func loadConfigFile(path, name string) (Config, error) {
+	binary, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+
+	var document interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(binary, &document)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(binary, &document)
+	case ".toml":
+		var table map[string]interface{}
+		_, err = toml.Decode(string(binary), &table)
+		document = table
+	default:
+		return Config{}, fmt.Errorf("config: unsupported file %s", path)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	// every format is decoded through json, so Config only need json tags
+	binary, err = json.Marshal(document)
+	if err != nil {
+		return Config{}, fmt.Errorf("config: %s: %w", path, err)
+	}
+	var file configFile
+	if err = json.Unmarshal(binary, &file); err != nil {
+		return Config{}, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if name == "" {
+		return file.Config, nil
+	}
+	config, ok := file.Clients[name]
+	if !ok {
+		return Config{}, fmt.Errorf("config: client %s not found in %s", name, path)
+	}
+	return config, nil
+}
+
+// configFromEnv it will override config with environment variables of prefix
+func configFromEnv(config *Config, prefix string, environ []string) error {
+	for _, variable := range environ {
+		pair := strings.SplitN(variable, "=", 2)
+		if len(pair) != 2 || !strings.HasPrefix(pair[0], prefix) {
+			continue
+		}
+		key, value := strings.TrimPrefix(pair[0], prefix), pair[1]
+		switch {
+		case key == "BASE_URL":
+			config.BaseUrl = value
+		case key == "TIMEOUT":
+			config.Timeout = value
+		case key == "USERNAME":
+			config.Username = value
+		case key == "PASSWORD":
+			config.Password = value
+		case key == "TOKEN":
+			config.Token = value
+		case strings.HasPrefix(key, "HEADER_"):
+			if config.Headers == nil {
+				config.Headers = make(map[string]string)
+			}
+			header := strings.ReplaceAll(strings.TrimPrefix(key, "HEADER_"), "_", "-")
+			config.Headers[http.CanonicalHeaderKey(header)] = value
+		case strings.HasPrefix(key, "RETRY_"):
+			if config.Retry == nil {
+				config.Retry = new(RetryConfig)
+			}
+			if err := configRetryFromEnv(config.Retry, strings.TrimPrefix(key, "RETRY_"), value); err != nil {
+				return fmt.Errorf("config: %s: %w", pair[0], err)
+			}
+		case strings.HasPrefix(key, "TLS_"):
+			if config.TLS == nil {
+				config.TLS = new(TLSConfig)
+			}
+			configTLSFromEnv(config.TLS, strings.TrimPrefix(key, "TLS_"), value)
+		}
+	}
+	return nil
+}
+
+func configRetryFromEnv(config *RetryConfig, key, value string) error {
+	switch key {
+	case "MAX_RETRIES":
+		retries, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		config.MaxRetries = retries
+	case "BACKOFF":
+		config.Backoff = value
+	case "MAX_BACKOFF":
+		config.MaxBackoff = value
+	}
+	return nil
+}
+
+func configTLSFromEnv(config *TLSConfig, key, value string) {
+	switch key {
+	case "CA_CERT":
+		config.CACert = strings.Split(value, ",")
+	case "CLIENT_CERT":
+		config.ClientCert = value
+	case "CLIENT_KEY":
+		config.ClientKey = value
+	case "MIN_VERSION":
+		config.MinVersion = value
+	case "PINS":
+		config.Pins = strings.Split(value, ",")
+	}
+}
+
+// options return options of config, with secrets resolved
+func (c Config) options() ([]Option, error) {
+	c, err := c.resolveSecrets()
+	if err != nil {
+		return nil, err
+	}
+	var options []Option
+	if c.BaseUrl != "" {
+		options = append(options, WithBaseUrl(c.BaseUrl))
+	}
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("config: timeout: %w", err)
+		}
+		options = append(options, WithTimeout(timeout))
+	}
+	for key, value := range c.Headers {
+		options = append(options, WithHeader(key, value))
+	}
+	if c.Username != "" || c.Password != "" {
+		options = append(options, WithAuthentication(c.Username, c.Password))
+	}
+	if c.Token != "" {
+		options = append(options, WithHeader("Authorization", "Bearer "+c.Token))
+	}
+	if c.Retry != nil {
+		retryOption, err := c.Retry.option()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, retryOption)
+	}
+	if c.TLS != nil {
+		tlsOptions, err := c.TLS.options()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, tlsOptions...)
+	}
+	return options, nil
+}
+
+func (c RetryConfig) option() (Option, error) {
+	policy := RetryPolicy{MaxRetries: c.MaxRetries}
+	var err error
+	if c.Backoff != "" {
+		if policy.Backoff, err = time.ParseDuration(c.Backoff); err != nil {
+			return nil, fmt.Errorf("config: retry: %w", err)
+		}
+	}
+	if c.MaxBackoff != "" {
+		if policy.MaxBackoff, err = time.ParseDuration(c.MaxBackoff); err != nil {
+			return nil, fmt.Errorf("config: retry: %w", err)
+		}
+	}
+	return WithRetry(policy), nil
+}
+
+func (c TLSConfig) options() ([]Option, error) {
+	var options []Option
+	if len(c.CACert) > 0 {
+		options = append(options, WithCACert(c.CACert...))
+	}
+	if c.ClientCert != "" || c.ClientKey != "" {
+		options = append(options, WithClientCert(c.ClientCert, c.ClientKey))
+	}
+	if c.MinVersion != "" {
+		version, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown tls version %s", c.MinVersion)
+		}
+		options = append(options, WithMinTLSVersion(version))
+	}
+	if len(c.Pins) > 0 {
+		options = append(options, WithPinnedKeys(c.Pins...))
+	}
+	return options, nil
+}
+
+// resolveSecrets return a copy of config with secrets
+// of every string value, including tls, resolved
+func (c Config) resolveSecrets() (Config, error) {
+	values := []*string{&c.BaseUrl, &c.Timeout, &c.Username, &c.Password, &c.Token}
+	headers := make(map[string]string, len(c.Headers))
+	for key, value := range c.Headers {
+		resolved, err := resolveSecret(value)
+		if err != nil {
+			return Config{}, err
+		}
+		headers[key] = resolved
+	}
+	c.Headers = headers
+	if c.Retry != nil {
+		retryConfig := *c.Retry
+		values = append(values, &retryConfig.Backoff, &retryConfig.MaxBackoff)
+		c.Retry = &retryConfig
+	}
+	if c.TLS != nil {
+		tlsConfig := *c.TLS
+		tlsConfig.CACert = append([]string(nil), tlsConfig.CACert...)
+		tlsConfig.Pins = append([]string(nil), tlsConfig.Pins...)
+		values = append(values, &tlsConfig.ClientCert, &tlsConfig.ClientKey, &tlsConfig.MinVersion)
+		for i := range tlsConfig.CACert {
+			values = append(values, &tlsConfig.CACert[i])
+		}
+		for i := range tlsConfig.Pins {
+			values = append(values, &tlsConfig.Pins[i])
+		}
+		c.TLS = &tlsConfig
+	}
+	for _, value := range values {
+		resolved, err := resolveSecret(*value)
+		if err != nil {
+			return Config{}, err
+		}
+		*value = resolved
+	}
+	return c, nil
+}
+
+// resolveSecret it will replace a reference to a secret,
+// ${env:NAME} or ${file:/path}, by its value
+func resolveSecret(value string) (string, error) {
+	if !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return value, nil
+	}
+	reference := strings.TrimSuffix(strings.TrimPrefix(value, "${"), "}")
+	switch {
+	case strings.HasPrefix(reference, "env:"):
+		name := strings.TrimPrefix(reference, "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("config: secret %s not found in environment", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(reference, "file:"):
+		secret, err := ioutil.ReadFile(strings.TrimPrefix(reference, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("config: secret: %w", err)
+		}
+		return strings.TrimRight(string(secret), "\r\n"), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,268 @@
+package pkg
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// setEnv it will set environment variables of pairs of
+// key and value, returned function unset them
+func setEnv(t *testing.T, pairs ...string) func() {
+	for i := 0; i < len(pairs); i += 2 {
+		assert.Nil(t, os.Setenv(pairs[i], pairs[i+1]))
+	}
+	return func() {
+		for i := 0; i < len(pairs); i += 2 {
+			_ = os.Unsetenv(pairs[i])
+		}
+	}
+}
+
+func newHeadersServer(received chan<- http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received <- request.Header
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[1]}`))
+	}))
+}
+
+func TestFromConfigJSON(t *testing.T) {
+	dir, remove := newTempDir(t)
+	defer remove()
+	path := writeConfig(t, dir, "config.json", `{
+		"clients": {
+			"users": {"baseUrl": "https://users.example.com", "timeout": "5s", "headers": {"X-Team": "core"}},
+			"orders": {"baseUrl": "https://orders.example.com"}
+		}
+	}`)
+
+	users, err := FromConfig(path, "users")
+	orders, _ := FromConfig(path, "orders")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://users.example.com", users.BaseUrl)
+	assert.Equal(t, 5*time.Second, users.Timeout)
+	assert.Equal(t, "core", users.Headers.Get("X-Team"))
+	assert.Equal(t, "https://orders.example.com", orders.BaseUrl)
+	assert.Empty(t, orders.Headers.Get("X-Team"))
+}
+
+func TestFromConfigYAML(t *testing.T) {
+	dir, remove := newTempDir(t)
+	defer remove()
+	path := writeConfig(t, dir, "config.yaml", `
+baseUrl: https://api.example.com
+timeout: 1m30s
+headers:
+  X-Team: core
+retry:
+  maxRetries: 3
+  backoff: 200ms
+tls:
+  minVersion: "1.2"
+`)
+
+	api, err := FromConfig(path, "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com", api.BaseUrl)
+	assert.Equal(t, 90*time.Second, api.Timeout)
+	assert.Equal(t, "core", api.Headers.Get("X-Team"))
+	assert.Equal(t, &RetryPolicy{MaxRetries: 3, Backoff: 200 * time.Millisecond}, api.retry)
+	assert.Equal(t, uint16(tls.VersionTLS12), api.transport.config.tls.MinVersion)
+}
+
+func TestFromConfigTOML(t *testing.T) {
+	dir, remove := newTempDir(t)
+	defer remove()
+	path := writeConfig(t, dir, "config.toml", `
+# clients of shop
+[clients.users]
+baseUrl = "https://users.example.com" # comment
+timeout = '2s'
+headers = { "X-Team" = "core#1" }
+tls = { pins = [
+  "2oLi9fXtFSmDD7wFBvvK8kGFYCNHzJ7XJrqNiQSsjZ0=",
+] }
+token = """
+abc"""
+
+[[clients.users.unknown]]
+name = "ignored"
+`)
+
+	api, err := FromConfig(path, "users")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://users.example.com", api.BaseUrl)
+	assert.Equal(t, 2*time.Second, api.Timeout)
+	assert.Equal(t, "core#1", api.Headers.Get("X-Team"))
+	assert.Equal(t, "Bearer abc", api.Headers.Get("Authorization"))
+	assert.NotNil(t, api.transport.config.tls.VerifyPeerCertificate)
+}
+
+func TestFromConfigEnvironmentOverridesFile(t *testing.T) {
+	dir, remove := newTempDir(t)
+	defer remove()
+	path := writeConfig(t, dir, "config.json", `{"clients": {"users": {"baseUrl": "https://users.example.com", "timeout": "5s"}}}`)
+	defer setEnv(t,
+		"APICALL_USERS_BASE_URL", "https://staging.example.com",
+		"APICALL_USERS_HEADER_X_REQUEST_SOURCE", "batch",
+		"APICALL_BASE_URL", "https://ignored.example.com",
+	)()
+
+	api, err := FromConfig(path, "users")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://staging.example.com", api.BaseUrl)
+	assert.Equal(t, 5*time.Second, api.Timeout)
+	assert.Equal(t, "batch", api.Headers.Get("X-Request-Source"))
+}
+
+func TestFromConfigOnlyEnvironment(t *testing.T) {
+	defer setEnv(t,
+		"APICALL_BASE_URL", "https://api.example.com",
+		"APICALL_TIMEOUT", "3s",
+		"APICALL_RETRY_MAX_RETRIES", "2",
+		"APICALL_RETRY_MAX_BACKOFF", "1s",
+	)()
+
+	api, err := FromConfig("", "", WithTimeout(time.Second))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com", api.BaseUrl)
+	assert.Equal(t, time.Second, api.Timeout)
+	assert.Equal(t, &RetryPolicy{MaxRetries: 2, MaxBackoff: time.Second}, api.retry)
+}
+
+func TestFromConfigSecrets(t *testing.T) {
+	dir, remove := newTempDir(t)
+	defer remove()
+	received := make(chan http.Header, 1)
+	ts := newHeadersServer(received)
+	defer ts.Close()
+	secret := writeConfig(t, dir, "password", "s3cret\n")
+	path := writeConfig(t, dir, "config.json", `{
+		"baseUrl": "`+ts.URL+`",
+		"username": "${env:APICALL_TEST_USERNAME}",
+		"password": "${file:`+secret+`}",
+		"headers": {"X-Api-Key": "${env:APICALL_TEST_KEY}"}
+	}`)
+	defer setEnv(t,
+		"APICALL_TEST_USERNAME", "jonathan",
+		"APICALL_TEST_KEY", "key",
+	)()
+
+	api, err := FromConfig(path, "")
+	assert.Nil(t, err)
+	response, err := api.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	headers := <-received
+	request := http.Request{Header: headers}
+	username, password, ok := request.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "jonathan", username)
+	assert.Equal(t, "s3cret", password)
+	assert.Equal(t, "key", headers.Get("X-Api-Key"))
+}
+
+func TestFromConfigResolveSecretsOfEveryValue(t *testing.T) {
+	dir, remove := newTempDir(t)
+	defer remove()
+	pin := writeConfig(t, dir, "pin", "2oLi9fXtFSmDD7wFBvvK8kGFYCNHzJ7XJrqNiQSsjZ0=\n")
+	path := writeConfig(t, dir, "config.json", `{
+		"baseUrl": "${env:APICALL_TEST_BASE_URL}",
+		"timeout": "${env:APICALL_TEST_TIMEOUT}",
+		"tls": {"minVersion": "${env:APICALL_TEST_TLS}", "pins": ["${file:`+pin+`}"]}
+	}`)
+	defer setEnv(t,
+		"APICALL_TEST_BASE_URL", "https://api.example.com",
+		"APICALL_TEST_TIMEOUT", "2s",
+		"APICALL_TEST_TLS", "1.3",
+	)()
+
+	api, err := FromConfig(path, "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com", api.BaseUrl)
+	assert.Equal(t, 2*time.Second, api.Timeout)
+	assert.Equal(t, uint16(tls.VersionTLS13), api.transport.config.tls.MinVersion)
+	assert.NotNil(t, api.transport.config.tls.VerifyPeerCertificate)
+}
+
+func TestFromConfigInvalidRetriesInEnvironment(t *testing.T) {
+	defer setEnv(t, "APICALL_RETRY_MAX_RETRIES", "many")()
+
+	api, err := FromConfig("", "")
+
+	assert.Nil(t, api)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "config: APICALL_RETRY_MAX_RETRIES")
+	}
+}
+
+func TestFromConfigToken(t *testing.T) {
+	received := make(chan http.Header, 1)
+	ts := newHeadersServer(received)
+	defer ts.Close()
+	defer setEnv(t,
+		"APICALL_BILLING_BASE_URL", ts.URL,
+		"APICALL_BILLING_TOKEN", "abc",
+	)()
+
+	api, err := FromConfig("", "billing")
+	assert.Nil(t, err)
+	_, err = api.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer abc", (<-received).Get("Authorization"))
+}
+
+func TestFromConfigErrors(t *testing.T) {
+	dir, remove := newTempDir(t)
+	defer remove()
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		client  string
+		err     string
+	}{
+		{"missing client", "config.json", `{"clients": {}}`, "users", "config: client users not found in"},
+		{"bad timeout", "config.json", `{"timeout": "soon"}`, "", "config: timeout"},
+		{"bad retry backoff", "config.json", `{"retry": {"backoff": "soon"}}`, "", "config: retry"},
+		{"negative retries", "config.json", `{"retry": {"maxRetries": -1}}`, "", "retry: MaxRetries must not be negative"},
+		{"tls version", "config.json", `{"tls": {"minVersion": "0.9"}}`, "", "config: unknown tls version 0.9"},
+		{"token and username", "config.json", `{"token": "a", "username": "b"}`, "", "authentication: Authorization header is already set"},
+		{"missing secret", "config.json", `{"token": "${env:APICALL_TEST_MISSING}"}`, "", "config: secret APICALL_TEST_MISSING not found"},
+		{"bad option", "config.json", `{"baseUrl": "ftp://example.com"}`, "", "base url"},
+		{"unsupported file", "config.ini", ``, "", "config: unsupported file"},
+		{"invalid yaml", "config.yml", "baseUrl: [", "", "config:"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, err := FromConfig(writeConfig(t, dir, test.file, test.content), test.client)
+
+			assert.Nil(t, api)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.err)
+			}
+		})
+	}
+}
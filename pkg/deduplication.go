@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultDeduplicationHeaders are headers which tell apart
+// identical requests when none is given to WithDeduplication
+var defaultDeduplicationHeaders = []string{"Authorization", "Accept", "Accept-Encoding"}
+
+type deduplicator struct {
+	headers []string
+	mu      sync.Mutex
+	calls   map[string]*deduplicatedCall
+}
+
+type deduplicatedCall struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	waiters  int
+	response *BaseStandard
+	err      error
+}
+
+// WithDeduplication it will collapse identical GET and HEAD requests in flight
+// into a single one, each caller get its own copy of response, requests are
+// identical when method, url and given headers are same
+func WithDeduplication(headers ...string) Option {
+	return func(a ApiCall) *ApiCall {
+		if len(headers) == 0 {
+			headers = defaultDeduplicationHeaders
+		}
+		a.deduplicator = newDeduplicator(headers)
+		return &a
+	}
+}
+
+func newDeduplicator(headers []string) *deduplicator {
+	return &deduplicator{headers: headers, calls: make(map[string]*deduplicatedCall)}
+}
+
+// fork return a deduplicator with same headers, which
+// doesn't share requests in flight with d
+func (d *deduplicator) fork() *deduplicator {
+	if d == nil {
+		return nil
+	}
+	return newDeduplicator(d.headers)
+}
+
+// accept return true if request can be deduplicated
+func (d *deduplicator) accept(method string, body io.Reader) bool {
+	return d != nil && body == nil && (method == http.MethodGet || method == http.MethodHead)
+}
+
+// key it will tell apart requests by method, url, headers of deduplicator
+// and headers overridden for that request, e.g. by SendWithHeaders
+func (d *deduplicator) key(method, url string, headers, overrides http.Header) string {
+	names := append([]string(nil), d.headers...)
+	for header := range overrides {
+		names = append(names, header)
+	}
+	sort.Strings(names[len(d.headers):])
+	key := []string{method, url}
+	for _, header := range names {
+		key = append(key, header+":"+strings.Join(headers[http.CanonicalHeaderKey(header)], ","))
+	}
+	return strings.Join(key, "\n")
+}
+
+// do it will call send only if there isn't a call for same key in flight,
+// otherwise it wait for that call and return a copy of its response, send
+// isn't tied to ctx of any caller, it is canceled once every caller gave up,
+// a caller which ctx is done get response of canceled
+func (d *deduplicator) do(ctx context.Context, key string, send func(context.Context) (*BaseStandard, error), canceled func(error) *BaseStandard) (*BaseStandard, error) {
+	d.mu.Lock()
+	call, ok := d.calls[key]
+	if !ok {
+		shared, cancel := context.WithCancel(context.Background())
+		call = &deduplicatedCall{done: make(chan struct{}), cancel: cancel}
+		d.calls[key] = call
+		go func() {
+			call.response, call.err = send(shared)
+			d.forget(key, call)
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	d.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.response.clone(), call.err
+	case <-ctx.Done():
+		d.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			d.forgetLocked(key, call)
+		}
+		d.mu.Unlock()
+		return canceled(ctx.Err()), nil
+	}
+}
+
+// forget it will remove call of key, so next caller start a new one
+func (d *deduplicator) forget(key string, call *deduplicatedCall) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.forgetLocked(key, call)
+}
+
+func (d *deduplicator) forgetLocked(key string, call *deduplicatedCall) {
+	if d.calls[key] == call {
+		delete(d.calls, key)
+	}
+}
+
+// clone it will make a deep copy of BaseStandard
+func (r *BaseStandard) clone() *BaseStandard {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	if r.Items != nil {
+		items := append(json.RawMessage(nil), *r.Items...)
+		c.Items = &items
+	}
+	c.rawItems = append([]byte(nil), r.rawItems...)
+	c.AuditInfo.Errors.Items = append(ItemsMeta(nil), r.AuditInfo.Errors.Items...)
+	c.AuditInfo.Info.Items = append(ItemsMeta(nil), r.AuditInfo.Info.Items...)
+	c.AuditInfo.Warning.Items = append(ItemsMeta(nil), r.AuditInfo.Warning.Items...)
+	c.AuditInfo.Redirects = append([]string(nil), r.AuditInfo.Redirects...)
+	if r.InterfaceSettings != nil {
+		if binary, err := json.Marshal(r.InterfaceSettings); err == nil {
+			c.InterfaceSettings = nil
+			_ = json.Unmarshal(binary, &c.InterfaceSettings)
+		}
+	}
+	if r.Metadata != nil {
+		c.Metadata = make(map[string]json.RawMessage, len(r.Metadata))
+		for key, value := range r.Metadata {
+			c.Metadata[key] = append(json.RawMessage(nil), value...)
+		}
+	}
+	if r.Problem != nil {
+		problem := *r.Problem
+		if r.Problem.Extensions != nil {
+			problem.Extensions = make(map[string]json.RawMessage, len(r.Problem.Extensions))
+			for key, value := range r.Problem.Extensions {
+				problem.Extensions[key] = append(json.RawMessage(nil), value...)
+			}
+		}
+		c.Problem = &problem
+	}
+	c.Header = r.Header.Clone()
+	if r.RawBody != nil {
+		c.RawBody = append([]byte(nil), r.RawBody...)
+	}
+	return &c
+}
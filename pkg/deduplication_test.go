@@ -0,0 +1,162 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newSlowConfigServer(calls *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt64(calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("ETag", `"abc"`)
+		_, _ = writer.Write([]byte(`{"items":{"feature":true},"auditInfo":{"warning":{"items":[{"code":"x01","description":"Deprecated"}]}}}`))
+	}))
+}
+
+func sendConcurrently(apicall *ApiCall, n int, method string, body func() *strings.Reader) []*BaseStandard {
+	responses := make([]*BaseStandard, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if body == nil {
+				responses[i], _ = apicall.Send(method, "/config", nil)
+				return
+			}
+			responses[i], _ = apicall.Send(method, "/config", body())
+		}(i)
+	}
+	wg.Wait()
+	return responses
+}
+
+func TestDeduplicateIdenticalRequests(t *testing.T) {
+	var calls int64
+	ts := newSlowConfigServer(&calls)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithDeduplication(),
+	)
+	responses := sendConcurrently(apicall, 5, "GET", nil)
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+	for _, response := range responses {
+		assert.True(t, response.IsOk())
+		assert.Equal(t, `{"feature":true}`, string(*response.Items))
+		assert.Equal(t, `"abc"`, response.Header.Get("ETag"))
+	}
+
+	responses[0].Warning.Items[0].Code = "changed"
+	(*responses[0].Items)[1] = 'X'
+	responses[0].Header.Set("ETag", "changed")
+	assert.Equal(t, "x01", responses[1].Warning.Items[0].Code)
+	assert.Equal(t, `{"feature":true}`, string(*responses[1].Items))
+	assert.Equal(t, `"abc"`, responses[1].Header.Get("ETag"))
+}
+
+func TestDeduplicationIsDisabledByDefault(t *testing.T) {
+	var calls int64
+	ts := newSlowConfigServer(&calls)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	sendConcurrently(apicall, 3, "GET", nil)
+
+	assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+}
+
+func TestDeduplicationIgnoreRequestsWithBody(t *testing.T) {
+	var calls int64
+	ts := newSlowConfigServer(&calls)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithDeduplication(),
+	)
+	sendConcurrently(apicall, 3, "POST", func() *strings.Reader {
+		return strings.NewReader(`{}`)
+	})
+
+	assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+}
+
+func TestDeduplicationKey(t *testing.T) {
+	d := &deduplicator{headers: []string{"Authorization"}}
+	first := http.Header{"Authorization": {"Bearer a"}, "X-Request-Id": {"1"}}
+	second := http.Header{"Authorization": {"Bearer a"}, "X-Request-Id": {"2"}}
+	third := http.Header{"Authorization": {"Bearer b"}}
+
+	assert.Equal(t, d.key("GET", "/config", first, nil), d.key("GET", "/config", second, nil))
+	assert.NotEqual(t, d.key("GET", "/config", first, nil), d.key("GET", "/config", third, nil))
+	assert.NotEqual(t, d.key("GET", "/config", first, nil), d.key("HEAD", "/config", first, nil))
+	assert.NotEqual(t, d.key("GET", "/config", first, nil), d.key("GET", "/users", first, nil))
+	assert.NotEqual(t,
+		d.key("GET", "/config", first, http.Header{"x-request-id": {"1"}}),
+		d.key("GET", "/config", second, http.Header{"X-Request-Id": {"2"}}),
+	)
+}
+
+func TestDeduplicationWaiterGiveUpOnItsContext(t *testing.T) {
+	var calls int64
+	ts := newSlowConfigServer(&calls)
+	defer ts.Close()
+	apicall := NewApiCall(WithBaseUrl(ts.URL), WithDeduplication())
+
+	first := make(chan *BaseStandard, 1)
+	go func() {
+		response, _ := apicall.Send("GET", "/config", nil)
+		first <- response
+	}()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&calls) == 1
+	}, time.Second, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	canceled, err := apicall.SendWithContext(ctx, "GET", "/config", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < 50*time.Millisecond)
+	assert.Equal(t, "Canceled", canceled.Errors.Items[0].Description)
+	assert.True(t, (<-first).IsOk())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+}
+
+func TestDeduplicationOutliveFirstCaller(t *testing.T) {
+	var calls int64
+	ts := newSlowConfigServer(&calls)
+	defer ts.Close()
+	apicall := NewApiCall(WithBaseUrl(ts.URL), WithDeduplication())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	first := make(chan *BaseStandard, 1)
+	go func() {
+		response, _ := apicall.SendWithContext(ctx, "GET", "/config", nil)
+		first <- response
+	}()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&calls) == 1
+	}, time.Second, time.Millisecond)
+	second, err := apicall.Send("GET", "/config", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, second.IsOk())
+	assert.Equal(t, "Timeout", (<-first).Errors.Items[0].Description)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+}
@@ -0,0 +1,54 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// DialFunc open a connection to address, like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// unixBaseUrl is BaseUrl of requests sent over an unix socket,
+// its host is ignored by dialer of WithUnixSocket
+const unixBaseUrl = "http://unix"
+
+// WithDialer it will open every connection with dial
+func WithDialer(dial DialFunc) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && dial == nil {
+			a.addError(errors.New("dialer: must not be nil"))
+			return &a
+		}
+		a.transport = a.transport.with(func(config *transportConfig) {
+			config.dial = dial
+		})
+		return &a
+	}
+}
+
+// WithUnixSocket it will send every request over unix socket of path,
+// BaseUrl can be any url, e.g. http://unix
+func WithUnixSocket(path string) Option {
+	var dialer net.Dialer
+	dial := WithDialer(func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	})
+	return func(a ApiCall) *ApiCall {
+		if a.strict && path == "" {
+			a.addError(errors.New("unix socket: path must not be empty"))
+			return &a
+		}
+		return dial(a)
+	}
+}
+
+// unixSocket return path of socket when base is
+// an unix url, e.g. unix:///var/run/api.sock
+func unixSocket(base string) (string, bool) {
+	if !strings.HasPrefix(base, "unix://") {
+		return "", false
+	}
+	return strings.TrimPrefix(base, "unix://"), true
+}
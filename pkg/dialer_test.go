@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// newUnixServer return path of a server listening on
+// an unix socket and a function to close it
+func newUnixServer(t *testing.T) (string, func()) {
+	dir, remove := newTempDir(t)
+	path := filepath.Join(dir, "api.sock")
+	listener, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":{"path":"` + request.URL.Path + `"}}`))
+	})}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	return path, func() {
+		_ = server.Close()
+		remove()
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	path, closeServer := newUnixServer(t)
+	defer closeServer()
+
+	tables := []struct {
+		name    string
+		options []Option
+	}{
+		{"unix url", []Option{WithBaseUrl("unix://" + path)}},
+		{"unix host", []Option{WithBaseUrl("http://unix"), WithUnixSocket(path)}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			apicall := NewApiCall(table.options...)
+			response, err := apicall.Send("GET", "/users", nil)
+
+			assert.Nil(t, err)
+			assert.True(t, response.IsOk())
+			assert.Equal(t, `{"path":"/users"}`, string(*response.Items))
+		})
+	}
+}
+
+func TestWithDialer(t *testing.T) {
+	ts := newTargetServer()
+	defer ts.Close()
+	var dials int64
+	var dialer net.Dialer
+
+	apicall := NewApiCall(
+		WithBaseUrl("http://api.internal"),
+		WithDialer(func(ctx context.Context, network, address string) (net.Conn, error) {
+			atomic.AddInt64(&dials, 1)
+			assert.Equal(t, "api.internal:80", address)
+			return dialer.DialContext(ctx, network, ts.Listener.Addr().String())
+		}),
+	)
+	response, _ := apicall.Send("GET", "/", nil)
+
+	assert.True(t, response.IsOk())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&dials))
+}
+
+func TestDialerIsNotUsedByDefault(t *testing.T) {
+	apicall := NewApiCall(WithBaseUrl("http://localhost"))
+
+	assert.Nil(t, apicall.transport)
+	assert.Equal(t, http.DefaultTransport, apicall.transport.roundTripper())
+}
@@ -0,0 +1,196 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Envelope decode a raw response into BaseStandard,
+// it must fill items, metadata and errors found in response
+type Envelope interface {
+	Decode(raw []byte, baseResponse *BaseStandard) error
+}
+
+var (
+	// StandardEnvelope decode items, auditInfo and interfaceSettings keys,
+	// it is used when no other envelope match response Content-Type
+	StandardEnvelope Envelope = standardEnvelope{}
+	// JSONAPIEnvelope decode a JSON:API document, data is used as items,
+	// errors as Errors and meta, links and included as Metadata
+	JSONAPIEnvelope Envelope = jsonAPIEnvelope{}
+	// HALEnvelope decode a HAL document, _embedded is used as items
+	// and _links as Metadata
+	HALEnvelope Envelope = halEnvelope{}
+	// ProblemEnvelope decode a RFC 7807 problem document into Errors
+	// and BaseStandard.Problem
+	ProblemEnvelope Envelope = problemEnvelope{}
+	// PlainEnvelope use the whole response as items
+	PlainEnvelope Envelope = plainEnvelope{}
+)
+
+var envelopes = struct {
+	sync.RWMutex
+	byMediaType map[string]Envelope
+}{
+	byMediaType: map[string]Envelope{
+		"application/vnd.api+json": JSONAPIEnvelope,
+		"application/hal+json":     HALEnvelope,
+		"application/problem+json": ProblemEnvelope,
+	},
+}
+
+// RegisterEnvelope it will use envelope for responses
+// with mediaType as Content-Type
+func RegisterEnvelope(mediaType string, envelope Envelope) {
+	envelopes.Lock()
+	defer envelopes.Unlock()
+	envelopes.byMediaType[mediaType] = envelope
+}
+
+// WithEnvelope it will modified ApiCall.Envelope field
+func WithEnvelope(envelope Envelope) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && envelope == nil {
+			a.addError(errors.New("envelope: must not be nil"))
+			return &a
+		}
+		a.Envelope = envelope
+		return &a
+	}
+}
+
+// envelopeFor return envelope which must decode a response,
+// ApiCall.Envelope has priority over response Content-Type,
+// except problems which are always decoded by ProblemEnvelope
+func envelopeFor(a *ApiCall, contentType string) Envelope {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if isProblem(mediaType) {
+		return ProblemEnvelope
+	}
+	if a.Envelope != nil {
+		return a.Envelope
+	}
+
+	envelopes.RLock()
+	defer envelopes.RUnlock()
+	if envelope, ok := envelopes.byMediaType[mediaType]; ok {
+		return envelope
+	}
+	return StandardEnvelope
+}
+
+// isProblem return true if mediaType is a RFC 7807 problem
+// in json, e.g. application/vnd.shop.problem+json
+func isProblem(mediaType string) bool {
+	return mediaType == "application/problem+json" ||
+		strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, ".problem+json")
+}
+
+type standardEnvelope struct{}
+
+func (standardEnvelope) Decode(raw []byte, baseResponse *BaseStandard) error {
+	return json.Unmarshal(raw, baseResponse)
+}
+
+type jsonAPIEnvelope struct{}
+
+func (jsonAPIEnvelope) Decode(raw []byte, baseResponse *BaseStandard) error {
+	var document struct {
+		Data   *json.RawMessage `json:"data"`
+		Errors []struct {
+			Status string `json:"status"`
+			Code   string `json:"code"`
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		} `json:"errors"`
+		Meta     json.RawMessage `json:"meta"`
+		Links    json.RawMessage `json:"links"`
+		Included json.RawMessage `json:"included"`
+	}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+
+	baseResponse.Items = document.Data
+	for _, e := range document.Errors {
+		code := e.Code
+		if code == "" {
+			code = e.Status
+		}
+		baseResponse.Errors.Items = append(baseResponse.Errors.Items, Meta{
+			Code:        code,
+			Description: joinDescription(e.Title, e.Detail),
+		})
+	}
+	setMetadata(baseResponse, "meta", document.Meta)
+	setMetadata(baseResponse, "links", document.Links)
+	setMetadata(baseResponse, "included", document.Included)
+	return nil
+}
+
+type halEnvelope struct{}
+
+func (halEnvelope) Decode(raw []byte, baseResponse *BaseStandard) error {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+
+	setMetadata(baseResponse, "_links", document["_links"])
+	embedded, ok := document["_embedded"]
+	if !ok {
+		delete(document, "_links")
+		items, err := json.Marshal(document)
+		if err != nil {
+			return err
+		}
+		baseResponse.Items = (*json.RawMessage)(&items)
+		return nil
+	}
+
+	// a single embedded relation is unwrapped, e.g. {"_embedded":{"users":[...]}}
+	var relations map[string]json.RawMessage
+	if err := json.Unmarshal(embedded, &relations); err == nil && len(relations) == 1 {
+		for _, relation := range relations {
+			embedded = relation
+		}
+	}
+	baseResponse.Items = &embedded
+	for key, value := range document {
+		if key != "_links" && key != "_embedded" {
+			setMetadata(baseResponse, key, value)
+		}
+	}
+	return nil
+}
+
+type plainEnvelope struct{}
+
+func (plainEnvelope) Decode(raw []byte, baseResponse *BaseStandard) error {
+	if !json.Valid(raw) {
+		return json.Unmarshal(raw, new(interface{}))
+	}
+	items := json.RawMessage(raw)
+	baseResponse.Items = &items
+	return nil
+}
+
+func setMetadata(baseResponse *BaseStandard, key string, value json.RawMessage) {
+	if len(value) == 0 {
+		return
+	}
+	if baseResponse.Metadata == nil {
+		baseResponse.Metadata = make(map[string]json.RawMessage)
+	}
+	baseResponse.Metadata[key] = value
+}
+
+func joinDescription(title, detail string) string {
+	if title == "" || detail == "" {
+		return title + detail
+	}
+	return title + ": " + detail
+}
@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newEnvelopeServer(contentType, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", contentType)
+		_, _ = writer.Write([]byte(body))
+	}))
+}
+
+func TestEnvelopeJSONAPIByContentType(t *testing.T) {
+	ts := newEnvelopeServer("application/vnd.api+json", `{"data":[{"type":"users","id":"1","attributes":{"name":"Jonathan"}}],"meta":{"total":1},"links":{"self":"/users"}}`)
+	defer ts.Close()
+	type User struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Name string `json:"name"`
+		} `json:"attributes"`
+	}
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/users", nil)
+	var users []User
+	errGetItems := response.GetItems(&users)
+
+	assert.Nil(t, err)
+	assert.Nil(t, errGetItems)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, "Jonathan", users[0].Attributes.Name)
+	assert.JSONEq(t, `{"total":1}`, string(response.Metadata["meta"]))
+	assert.JSONEq(t, `{"self":"/users"}`, string(response.Metadata["links"]))
+}
+
+func TestEnvelopeJSONAPIErrors(t *testing.T) {
+	ts := newEnvelopeServer("application/vnd.api+json", `{"errors":[{"status":"422","title":"Invalid Attribute","detail":"Name is required"},{"code":"x01","title":"Forbidden"}]}`)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("POST", "/users", nil)
+
+	assert.Nil(t, err)
+	assert.False(t, response.IsOk())
+	assert.Equal(t, "[422]: Invalid Attribute: Name is required, [x01]: Forbidden", response.AuditInfo.Errors.String())
+}
+
+func TestEnvelopeHALUnwrapEmbedded(t *testing.T) {
+	ts := newEnvelopeServer("application/hal+json", `{"_links":{"self":{"href":"/users"}},"_embedded":{"users":[{"name":"Jonathan"}]},"total":1}`)
+	defer ts.Close()
+	type User struct {
+		Name string `json:"name"`
+	}
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/users", nil)
+	var users []User
+	errGetItems := response.GetItems(&users)
+
+	assert.Nil(t, err)
+	assert.Nil(t, errGetItems)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, []User{{"Jonathan"}}, users)
+	assert.JSONEq(t, `{"self":{"href":"/users"}}`, string(response.Metadata["_links"]))
+	assert.Equal(t, "1", string(response.Metadata["total"]))
+}
+
+func TestEnvelopeHALWithoutEmbedded(t *testing.T) {
+	ts := newEnvelopeServer("application/hal+json", `{"_links":{"self":{"href":"/users/1"}},"name":"Jonathan"}`)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/users/1", nil)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"name":"Jonathan"}`, string(*response.Items))
+}
+
+func TestEnvelopeProblemByContentType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/problem+json")
+		writer.WriteHeader(http.StatusForbidden)
+		_, _ = writer.Write([]byte(`{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403}`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/account", nil)
+
+	assert.Nil(t, err)
+	assert.False(t, response.IsOk())
+	assert.Equal(t, "[https://example.com/probs/out-of-credit]: You do not have enough credit.", response.AuditInfo.Errors.String())
+}
+
+func TestEnvelopeProblemWithOtherEnvelope(t *testing.T) {
+	tables := []struct {
+		envelope    Envelope
+		contentType string
+	}{
+		{JSONAPIEnvelope, "application/problem+json"},
+		{PlainEnvelope, "application/problem+json; charset=utf-8"},
+		{PlainEnvelope, "application/vnd.shop.problem+json"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.contentType, func(t *testing.T) {
+			ts := newEnvelopeServer(table.contentType, `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403}`)
+			defer ts.Close()
+
+			apicall := NewApiCall(
+				WithBaseUrl(ts.URL),
+				WithEnvelope(table.envelope),
+			)
+			response, err := apicall.Send("GET", "/account", nil)
+
+			assert.Nil(t, err)
+			assert.False(t, response.IsOk())
+			assert.NotNil(t, response.Problem)
+			assert.Equal(t, "[https://example.com/probs/out-of-credit]: You do not have enough credit.", response.AuditInfo.Errors.String())
+		})
+	}
+}
+
+func TestEnvelopePlainWithOption(t *testing.T) {
+	ts := newEnvelopeServer("application/json", `[{"name":"Jonathan"}]`)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithEnvelope(PlainEnvelope),
+	)
+	response, err := apicall.Send("GET", "/users", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, `[{"name":"Jonathan"}]`, string(*response.Items))
+}
+
+func TestEnvelopePlainWithInvalidJson(t *testing.T) {
+	ts := newEnvelopeServer("application/json", `Hello World`)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithEnvelope(PlainEnvelope),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.False(t, response.IsOk())
+	assert.Equal(t, "[syntaxerror]: invalid character 'H' looking for beginning of value[1] - Hello World", response.AuditInfo.Errors.String())
+}
+
+func TestRegisterEnvelope(t *testing.T) {
+	ts := newEnvelopeServer("application/vnd.plain+json", `{"name":"Jonathan"}`)
+	defer ts.Close()
+	RegisterEnvelope("application/vnd.plain+json", PlainEnvelope)
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, `{"name":"Jonathan"}`, string(*response.Items))
+}
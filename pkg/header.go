@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Version is version of api-call, sent in default User-Agent
+const Version = "0.1.0"
+
+// defaultUserAgent is User-Agent of requests unless WithUserAgent is used
+const defaultUserAgent = "api-call/" + Version
+
+// WithHeader it will set header key of every request,
+// replacing values given before to same key
+func WithHeader(key, value string) Option {
+	return WithHeaders(http.Header{key: {value}})
+}
+
+// WithHeaders it will set every header of headers in every
+// request, replacing values given before to same keys, a
+// Content-Type given here is sent instead of ApiCall.ContentType
+func WithHeaders(headers http.Header) Option {
+	return func(a ApiCall) *ApiCall {
+		for key, values := range headers {
+			if err := validateHeader(key, values); a.strict && err != nil {
+				a.addError(err)
+				return &a
+			}
+		}
+		a.Headers = a.Headers.Clone()
+		if a.Headers == nil {
+			a.Headers = make(http.Header)
+		}
+		for key, values := range headers {
+			a.Headers[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+		return &a
+	}
+}
+
+// WithUserAgent it will send agent as User-Agent instead of
+// api-call/Version, when agent is empty no User-Agent is sent
+func WithUserAgent(agent string) Option {
+	return WithHeader("User-Agent", agent)
+}
+
+// validateHeader return an error when key isn't a header
+// name or a value would break request into another line
+func validateHeader(key string, values []string) error {
+	if key == "" {
+		return errors.New("header: key must not be empty")
+	}
+	if strings.IndexFunc(key, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r)
+	}) >= 0 {
+		return fmt.Errorf("header: invalid key %q", key)
+	}
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header: value of %s must not contain new lines", key)
+		}
+	}
+	return nil
+}
+
+// SendWithHeaders it will send a request like SendWithContext, with
+// headers replacing those of ApiCall with same key, a key without
+// values remove that header from request
+func (a *ApiCall) SendWithHeaders(ctx context.Context, method, url string, body io.Reader, headers http.Header) (*BaseStandard, error) {
+	for key, values := range headers {
+		if err := validateHeader(key, values); err != nil {
+			return nil, err
+		}
+	}
+	return a.sendWithHeaders(ctx, method, url, body, headers)
+}
+
+// overrideHeaders it will replace headers by those of overrides
+func overrideHeaders(headers, overrides http.Header) {
+	for key, values := range overrides {
+		key = http.CanonicalHeaderKey(key)
+		if len(values) == 0 {
+			headers.Del(key)
+			continue
+		}
+		headers[key] = append([]string(nil), values...)
+	}
+}
@@ -0,0 +1,156 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	ts := newHeadersServer(received)
+	defer ts.Close()
+	original := NewApiCall(WithBaseUrl(ts.URL), WithHeader("x-team", "core"))
+
+	apicall := original.With(WithHeaders(http.Header{
+		"X-Team":   {"payments"},
+		"X-Region": {"eu", "us"},
+	}))
+	_, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	headers := <-received
+	assert.Equal(t, []string{"payments"}, headers["X-Team"])
+	assert.Equal(t, []string{"eu", "us"}, headers["X-Region"])
+	assert.Equal(t, http.Header{"X-Team": {"core"}}, original.Headers)
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	received := make(chan http.Header, 3)
+	ts := newHeadersServer(received)
+	defer ts.Close()
+
+	_, _ = NewApiCall(WithBaseUrl(ts.URL)).Send("GET", "/", nil)
+	_, _ = NewApiCall(WithBaseUrl(ts.URL), WithUserAgent("billing/2.0")).Send("GET", "/", nil)
+	_, _ = NewApiCall(WithBaseUrl(ts.URL), WithUserAgent("")).Send("GET", "/", nil)
+
+	assert.Equal(t, "api-call/"+Version, (<-received).Get("User-Agent"))
+	assert.Equal(t, "billing/2.0", (<-received).Get("User-Agent"))
+	assert.Empty(t, (<-received).Get("User-Agent"))
+}
+
+func TestSendWithHeadersOverrideHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	ts := newHeadersServer(received)
+	defer ts.Close()
+	apicall := NewApiCall(WithBaseUrl(ts.URL), WithHeaders(http.Header{
+		"X-Team":   {"core"},
+		"X-Region": {"eu"},
+	}))
+
+	_, err := apicall.SendWithHeaders(context.Background(), "GET", "/", nil, http.Header{
+		"x-team":     {"payments"},
+		"X-Region":   nil,
+		"X-Trace-Id": {"abc"},
+	})
+
+	assert.Nil(t, err)
+	headers := <-received
+	assert.Equal(t, "payments", headers.Get("X-Team"))
+	assert.Empty(t, headers.Get("X-Region"))
+	assert.Equal(t, "abc", headers.Get("X-Trace-Id"))
+	assert.Equal(t, http.Header{"X-Team": {"core"}, "X-Region": {"eu"}}, apicall.Headers)
+}
+
+func TestSendAllWithHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	ts := newHeadersServer(received)
+	defer ts.Close()
+
+	_, err := NewApiCall(WithBaseUrl(ts.URL)).SendAll(context.Background(), []Request{
+		{Method: "GET", Url: "/", Headers: http.Header{"X-Tenant": {"acme"}}},
+	}, BatchOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "acme", (<-received).Get("X-Tenant"))
+}
+
+func TestSendNeverChangeHeaders(t *testing.T) {
+	received := make(chan http.Header, 3)
+	ts := newHeadersServer(received)
+	defer ts.Close()
+	apicall := NewApiCall(WithBaseUrl(ts.URL), WithHeader("X-Team", "core"), WithCompression("gzip", 0))
+
+	for i := 0; i < 3; i++ {
+		_, err := apicall.Send("POST", "/", strings.NewReader(`{"name":"jonathan"}`))
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"application/json; charset=UTF-8"}, (<-received)["Content-Type"])
+	}
+
+	assert.Equal(t, http.Header{"X-Team": {"core"}}, apicall.Headers)
+}
+
+func TestHeaderContentTypeIsKept(t *testing.T) {
+	received := make(chan http.Header, 2)
+	ts := newHeadersServer(received)
+	defer ts.Close()
+
+	_, _ = NewApiCall(WithBaseUrl(ts.URL), WithHeader("Content-Type", "application/vnd.shop+json")).Send("POST", "/", nil)
+	_, _ = NewApiCall(WithBaseUrl(ts.URL)).Send("POST", "/", nil)
+
+	assert.Equal(t, []string{"application/vnd.shop+json"}, (<-received)["Content-Type"])
+	assert.Equal(t, []string{"application/json; charset=UTF-8"}, (<-received)["Content-Type"])
+}
+
+func TestNewApiCallDoesNotCheckHeaders(t *testing.T) {
+	apicall := NewApiCall(WithHeader("X Team", "core"))
+
+	assert.Empty(t, apicall.errs)
+	assert.Equal(t, "core", apicall.Headers.Get("X Team"))
+}
+
+func TestHeaderErrors(t *testing.T) {
+	tables := []struct {
+		option Option
+		error  string
+	}{
+		{WithHeader("", "value"), "header: key must not be empty"},
+		{WithHeader("X Team", "value"), `header: invalid key "X Team"`},
+		{WithHeader("X-Team", "core\r\nX-Admin: true"), "header: value of X-Team must not contain new lines"},
+		{WithUserAgent("agent\n"), "header: value of User-Agent must not contain new lines"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.error, func(t *testing.T) {
+			_, err := New(table.option)
+
+			assert.EqualError(t, err, table.error)
+		})
+	}
+
+	_, err := NewApiCall().SendWithHeaders(context.Background(), "GET", "/", nil, http.Header{"": {"value"}})
+	assert.EqualError(t, err, "header: key must not be empty")
+}
+
+func TestSendWithHeadersAreNotDeduplicatedTogether(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":["` + request.Header.Get("X-Tenant") + `"]}`))
+	}))
+	defer ts.Close()
+	apicall := NewApiCall(WithBaseUrl(ts.URL), WithDeduplication())
+
+	responses, err := apicall.SendAll(context.Background(), []Request{
+		{Method: "GET", Url: "/", Headers: http.Header{"X-Tenant": {"acme"}}},
+		{Method: "GET", Url: "/", Headers: http.Header{"X-Tenant": {"globex"}}},
+	}, BatchOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, `["acme"]`, string(*responses[0].Items))
+	assert.Equal(t, `["globex"]`, string(*responses[1].Items))
+}
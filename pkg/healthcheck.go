@@ -0,0 +1,222 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHealthCheckInterval is Interval of a HealthCheck without one
+const defaultHealthCheckInterval = 10 * time.Second
+
+// HealthCheck configure probes made by WithHealthCheck
+type HealthCheck struct {
+	// Path probed on each endpoint, e.g. /health, a 2xx response is healthy
+	Path string
+	// Interval between probes, when zero 10 seconds is used
+	Interval time.Duration
+	// Timeout of each probe, when zero Interval is used
+	Timeout time.Duration
+	// HealthyThreshold is how many consecutive successes make
+	// an unhealthy endpoint healthy, when zero 2 is used
+	HealthyThreshold int
+	// UnhealthyThreshold is how many consecutive failures make
+	// a healthy endpoint unhealthy, when zero 3 is used
+	UnhealthyThreshold int
+	// OnChange is called when an endpoint become healthy or unhealthy
+	OnChange func(status EndpointStatus)
+}
+
+// EndpointStatus is health of an endpoint
+type EndpointStatus struct {
+	// Url is base url of endpoint
+	Url string
+	// Healthy is false when endpoint failed its health checks
+	Healthy bool
+	// Ejected is true when endpoint failed too many requests, see WithEjection
+	Ejected bool
+	// Error of last health check, empty when it succeeded
+	Error string
+}
+
+type healthChecker struct {
+	check    HealthCheck
+	balancer *balancer
+	client   *http.Client
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// WithHealthCheck it will probe endpoints of WithBaseUrls, or BaseUrl,
+// in background until Close is called, unhealthy endpoints don't
+// receive requests while there are healthy ones
+func WithHealthCheck(check HealthCheck) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && !strings.HasPrefix(check.Path, "/") {
+			a.addError(fmt.Errorf("health check: path must start with /, got %q", check.Path))
+			return &a
+		}
+		if a.strict && (check.Interval < 0 || check.Timeout < 0 || check.HealthyThreshold < 0 || check.UnhealthyThreshold < 0) {
+			a.addError(errors.New("health check: interval, timeout and thresholds must not be negative"))
+			return &a
+		}
+		// NewApiCall don't check values, negative ones are defaults too
+		if check.Interval <= 0 {
+			check.Interval = defaultHealthCheckInterval
+		}
+		if check.Timeout <= 0 {
+			check.Timeout = check.Interval
+		}
+		if check.HealthyThreshold <= 0 {
+			check.HealthyThreshold = 2
+		}
+		if check.UnhealthyThreshold <= 0 {
+			check.UnhealthyThreshold = 3
+		}
+		a.healthCheck = &check
+		return &a
+	}
+}
+
+// startHealthCheck it will start health checker when there is
+// a health check configured, it is called once options are applied
+func (a *ApiCall) startHealthCheck() {
+	if a.healthCheck == nil || a.checker != nil {
+		return
+	}
+	b := a.balancer
+	if b == nil {
+		b = &balancer{endpoints: []*endpoint{{url: a.BaseUrl}}}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	checker := &healthChecker{
+		check:    *a.healthCheck,
+		balancer: b,
+		client:   a.client(),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	if !a.lifecycle.track(checker) {
+		cancel()
+		return
+	}
+	a.checker = checker
+	go a.checker.run()
+}
+
+// EndpointStatus return health of each endpoint, of WithBaseUrls or
+// BaseUrl when there is a health check, in order of urls
+func (a *ApiCall) EndpointStatus() []EndpointStatus {
+	b := a.balancer
+	if a.checker != nil {
+		b = a.checker.balancer
+	}
+	if b == nil {
+		return nil
+	}
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := make([]EndpointStatus, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		status = append(status, e.status(now))
+	}
+	return status
+}
+
+func (h *healthChecker) run() {
+	defer close(h.done)
+	// time.NewTicker panic on a non-positive interval, which
+	// would kill the process from this goroutine
+	interval := h.check.Interval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.probeAll()
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *healthChecker) stop() {
+	if h == nil {
+		return
+	}
+	h.cancel()
+	<-h.done
+}
+
+func (h *healthChecker) probeAll() {
+	var wg sync.WaitGroup
+	for _, e := range h.balancer.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			err := h.probe(e)
+			if h.ctx.Err() == nil {
+				h.record(e, err)
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+func (h *healthChecker) probe(e *endpoint) error {
+	ctx, cancel := context.WithTimeout(h.ctx, h.check.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+h.check.Path, nil)
+	if err != nil {
+		return err
+	}
+	response, err := h.client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("health check: %s", response.Status)
+	}
+	return nil
+}
+
+// record it will change health of e, after enough
+// consecutive probes with same result
+func (h *healthChecker) record(e *endpoint, err error) {
+	h.balancer.mu.Lock()
+	changed := false
+	e.probeError = ""
+	if err == nil {
+		e.probeFailures = 0
+		e.probeSuccesses++
+		if e.unhealthy && e.probeSuccesses >= h.check.HealthyThreshold {
+			e.unhealthy = false
+			changed = true
+		}
+	} else {
+		e.probeError = err.Error()
+		e.probeSuccesses = 0
+		e.probeFailures++
+		if !e.unhealthy && e.probeFailures >= h.check.UnhealthyThreshold {
+			e.unhealthy = true
+			changed = true
+		}
+	}
+	status := e.status(time.Now())
+	h.balancer.mu.Unlock()
+
+	if changed && h.check.OnChange != nil {
+		h.check.OnChange(status)
+	}
+}
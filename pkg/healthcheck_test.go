@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newToggleServer return a server which /health respond
+// 200 while healthy is 1, otherwise 503
+func newToggleServer(healthy *int32, probes *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/health" {
+			writer.Header().Set("Content-Type", "application/json")
+			_, _ = writer.Write([]byte(`{"items":[]}`))
+			return
+		}
+		atomic.AddInt64(probes, 1)
+		if atomic.LoadInt32(healthy) == 0 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+}
+
+func TestHealthCheckMarkEndpoints(t *testing.T) {
+	healthyA, healthyB := int32(1), int32(1)
+	var probes int64
+	a := newToggleServer(&healthyA, &probes)
+	defer a.Close()
+	b := newToggleServer(&healthyB, &probes)
+	defer b.Close()
+	var mu sync.Mutex
+	var changes []EndpointStatus
+
+	apicall := NewApiCall(
+		WithBaseUrls([]string{a.URL, b.URL}, RoundRobin()),
+		WithHealthCheck(HealthCheck{
+			Path:               "/health",
+			Interval:           10 * time.Millisecond,
+			HealthyThreshold:   2,
+			UnhealthyThreshold: 2,
+			OnChange: func(status EndpointStatus) {
+				mu.Lock()
+				defer mu.Unlock()
+				changes = append(changes, status)
+			},
+		}),
+	)
+	defer apicall.Close(context.Background())
+
+	atomic.StoreInt32(&healthyA, 0)
+	assert.Eventually(t, func() bool {
+		return !apicall.EndpointStatus()[0].Healthy
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{b.URL, b.URL, b.URL}, sendEndpoints(apicall, 3))
+	assert.Equal(t, "health check: 503 Service Unavailable", apicall.EndpointStatus()[0].Error)
+	assert.True(t, apicall.EndpointStatus()[1].Healthy)
+
+	atomic.StoreInt32(&healthyA, 1)
+	assert.Eventually(t, func() bool {
+		return apicall.EndpointStatus()[0].Healthy
+	}, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []EndpointStatus{
+		{Url: a.URL, Healthy: false, Error: "health check: 503 Service Unavailable"},
+		{Url: a.URL, Healthy: true},
+	}, changes)
+}
+
+func TestHealthCheckOfBaseUrl(t *testing.T) {
+	healthy := int32(0)
+	var probes int64
+	ts := newToggleServer(&healthy, &probes)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithHealthCheck(HealthCheck{Path: "/health", Interval: 10 * time.Millisecond, UnhealthyThreshold: 1}),
+	)
+	defer apicall.Close(context.Background())
+
+	assert.Eventually(t, func() bool {
+		return !apicall.EndpointStatus()[0].Healthy
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, ts.URL, apicall.EndpointStatus()[0].Url)
+}
+
+func TestCloseStopHealthCheck(t *testing.T) {
+	healthy := int32(1)
+	var probes int64
+	ts := newToggleServer(&healthy, &probes)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithHealthCheck(HealthCheck{Path: "/health", Interval: 5 * time.Millisecond}),
+	)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&probes) > 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Nil(t, apicall.Close(context.Background()))
+	// a probe canceled by Close may still reach server
+	time.Sleep(10 * time.Millisecond)
+	stopped := atomic.LoadInt64(&probes)
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, stopped, atomic.LoadInt64(&probes))
+}
+
+func TestNewApiCallUseDefaultsOfNegativeHealthCheck(t *testing.T) {
+	apicall := NewApiCall(
+		WithBaseUrl("http://localhost"),
+		WithHealthCheck(HealthCheck{Path: "/health", Interval: -time.Second, Timeout: -time.Second, UnhealthyThreshold: -1}),
+	)
+	defer apicall.Close(context.Background())
+
+	assert.Empty(t, apicall.errs)
+	assert.Equal(t, HealthCheck{Path: "/health", Interval: 10 * time.Second, Timeout: 10 * time.Second, HealthyThreshold: 2, UnhealthyThreshold: 3}, *apicall.healthCheck)
+}
+
+func TestHealthCheckerNeverTickOnNonPositiveInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	checker := &healthChecker{
+		check:    HealthCheck{Path: "/health"},
+		balancer: &balancer{},
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	// a panic of ticker would crash whole test binary
+	go checker.run()
+	checker.stop()
+}
+
+func TestEndpointStatusWithoutHealthCheck(t *testing.T) {
+	assert.Nil(t, NewApiCall(WithBaseUrl("http://localhost")).EndpointStatus())
+	assert.Nil(t, NewApiCall().Close(context.Background()))
+}
+
+func TestWithCheckOwnBaseUrl(t *testing.T) {
+	healthy, unhealthy := int32(1), int32(0)
+	var probes, derivedProbes int64
+	ts := newToggleServer(&healthy, &probes)
+	defer ts.Close()
+	other := newToggleServer(&unhealthy, &derivedProbes)
+	defer other.Close()
+
+	original := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithHealthCheck(HealthCheck{Path: "/health", Interval: 5 * time.Millisecond, UnhealthyThreshold: 1}),
+	)
+	derived := original.With(WithBaseUrl(other.URL))
+
+	assert.Eventually(t, func() bool {
+		return !derived.EndpointStatus()[0].Healthy
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, other.URL, derived.EndpointStatus()[0].Url)
+	assert.True(t, original.EndpointStatus()[0].Healthy)
+
+	assert.Nil(t, derived.Close(context.Background()))
+	time.Sleep(10 * time.Millisecond)
+	stopped, running := atomic.LoadInt64(&derivedProbes), atomic.LoadInt64(&probes)
+	time.Sleep(30 * time.Millisecond)
+	_, err := derived.Send("GET", "/", nil)
+
+	assert.Equal(t, stopped, atomic.LoadInt64(&derivedProbes))
+	assert.Greater(t, atomic.LoadInt64(&probes), running)
+	assert.Equal(t, ErrClosed, err)
+	assert.Nil(t, original.Close(context.Background()))
+}
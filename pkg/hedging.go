@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// HedgeStats count how hedged requests behaved
+type HedgeStats struct {
+	// Requests is how many requests could be hedged
+	Requests int64
+	// Hedged is how many times a second request was sent
+	Hedged int64
+	// HedgeWins is how many times second request answered first
+	HedgeWins int64
+}
+
+type hedger struct {
+	delay time.Duration
+	stats HedgeStats
+}
+
+type hedgeResult struct {
+	response *http.Response
+	err      error
+	index    int
+}
+
+// WithHedging it will send a second request when first one hasn't
+// responded after delay, first response is used and other is canceled,
+// only GET, HEAD and OPTIONS requests without body are hedged
+func WithHedging(delay time.Duration) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && delay <= 0 {
+			a.addError(fmt.Errorf("hedging: delay must be positive, got %v", delay))
+			return &a
+		}
+		a.hedger = &hedger{delay: delay}
+		return &a
+	}
+}
+
+// HedgeStats return how many requests were hedged and
+// how many times hedged request won
+func (a *ApiCall) HedgeStats() HedgeStats {
+	if a.hedger == nil {
+		return HedgeStats{}
+	}
+	return HedgeStats{
+		Requests:  atomic.LoadInt64(&a.hedger.stats.Requests),
+		Hedged:    atomic.LoadInt64(&a.hedger.stats.Hedged),
+		HedgeWins: atomic.LoadInt64(&a.hedger.stats.HedgeWins),
+	}
+}
+
+// do it will call send, hedging it when request can be hedged
+func (h *hedger) do(ctx context.Context, method string, body io.Reader, send func(ctx context.Context) (*http.Response, error)) (*http.Response, error) {
+	if h == nil || body != nil || (method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions) {
+		return send(ctx)
+	}
+	atomic.AddInt64(&h.stats.Requests, 1)
+
+	results := make(chan hedgeResult, 2)
+	cancels := make([]context.CancelFunc, 0, 2)
+	launch := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			response, err := send(attemptCtx)
+			results <- hedgeResult{response, err, index}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) == 1 {
+				atomic.AddInt64(&h.stats.Hedged, 1)
+				launch()
+				pending++
+			}
+		case result := <-results:
+			pending--
+			if result.err != nil && pending > 0 {
+				continue
+			}
+			for index, cancel := range cancels {
+				if index != result.index {
+					cancel()
+				}
+			}
+			go discardResults(results, pending)
+			if result.err != nil {
+				cancels[result.index]()
+				return nil, result.err
+			}
+			if result.index > 0 {
+				atomic.AddInt64(&h.stats.HedgeWins, 1)
+			}
+			result.response.Body = &cancelOnClose{result.response.Body, cancels[result.index]}
+			return result.response, nil
+		}
+	}
+}
+
+// discardResults it will close bodies of requests which lost
+func discardResults(results chan hedgeResult, pending int) {
+	for i := 0; i < pending; i++ {
+		result := <-results
+		if result.response != nil {
+			_ = result.response.Body.Close()
+		}
+	}
+}
+
+// cancelOnClose cancel context of request when body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
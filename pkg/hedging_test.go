@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newLatencyServer respond each call after latency of same index,
+// canceled count calls which client gave up
+func newLatencyServer(latencies []time.Duration, calls, canceled *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		call := atomic.AddInt64(calls, 1) - 1
+		latency := latencies[len(latencies)-1]
+		if int(call) < len(latencies) {
+			latency = latencies[call]
+		}
+		select {
+		case <-time.After(latency):
+		case <-request.Context().Done():
+			atomic.AddInt64(canceled, 1)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[{"call":` + strconv.FormatInt(call, 10) + `}]}`))
+	}))
+}
+
+func TestHedgedRequestWin(t *testing.T) {
+	var calls, canceled int64
+	ts := newLatencyServer([]time.Duration{time.Second, 0}, &calls, &canceled)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithHedging(50*time.Millisecond),
+	)
+	start := time.Now()
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, `[{"call":1}]`, string(*response.Items))
+	assert.Equal(t, HedgeStats{Requests: 1, Hedged: 1, HedgeWins: 1}, apicall.HedgeStats())
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&canceled) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestHedgedRequestPrimaryWin(t *testing.T) {
+	var calls, canceled int64
+	ts := newLatencyServer([]time.Duration{100 * time.Millisecond, time.Second}, &calls, &canceled)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithHedging(20*time.Millisecond),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"call":0}]`, string(*response.Items))
+	assert.Equal(t, HedgeStats{Requests: 1, Hedged: 1, HedgeWins: 0}, apicall.HedgeStats())
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&canceled) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestHedgeIsNotSentForFastResponse(t *testing.T) {
+	var calls, canceled int64
+	ts := newLatencyServer([]time.Duration{0}, &calls, &canceled)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithHedging(100*time.Millisecond),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+	assert.Equal(t, HedgeStats{Requests: 1}, apicall.HedgeStats())
+}
+
+func TestHedgeIsNotSentForRequestWithBody(t *testing.T) {
+	var calls, canceled int64
+	ts := newLatencyServer([]time.Duration{100 * time.Millisecond}, &calls, &canceled)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithHedging(10*time.Millisecond),
+	)
+	response, err := apicall.Send("POST", "/", strings.NewReader(`{}`))
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+	assert.Equal(t, HedgeStats{}, apicall.HedgeStats())
+}
+
+func TestHedgedRequestTimeout(t *testing.T) {
+	var calls, canceled int64
+	ts := newLatencyServer([]time.Duration{time.Second}, &calls, &canceled)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithTimeout(100*time.Millisecond),
+		WithHedging(20*time.Millisecond),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Timeout", response.AuditInfo.Errors.Items[0].Description)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&calls))
+}
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrClosed is returned by Send once Close was called
+var ErrClosed = errors.New("api call is closed")
+
+// lifecycle track requests in flight, so Close can wait for them
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+	// checkers are health checks started by ApiCall, stopped on close
+	checkers []*healthChecker
+	// shared is transport inherited from ApiCall derived by With,
+	// its connections are closed by that ApiCall
+	shared *transport
+}
+
+// Close it will refuse new requests with ErrClosed, wait for requests in
+// flight until ctx is done, stop health checks and close idle connections,
+// error of ctx is returned when requests in flight didn't finish in time,
+// a client derived by With only close what isn't shared with its parent
+func (a *ApiCall) Close(ctx context.Context) error {
+	err := a.lifecycle.close(ctx)
+	if a.lifecycle == nil {
+		a.checker.stop()
+	}
+	a.lifecycle.stopCheckers()
+	if a.lifecycle == nil || a.transport != a.lifecycle.shared {
+		a.transport.closeIdleConnections()
+	}
+	return err
+}
+
+// enter it will count a request in flight, ErrClosed is returned
+// when Close was already called
+func (l *lifecycle) enter() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	l.inFlight.Add(1)
+	return nil
+}
+
+func (l *lifecycle) leave() {
+	if l != nil {
+		l.inFlight.Done()
+	}
+}
+
+func (l *lifecycle) close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		l.inFlight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track it will stop checker on close, false is
+// returned when Close was already called
+func (l *lifecycle) track(checker *healthChecker) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.checkers = append(l.checkers, checker)
+	return true
+}
+
+func (l *lifecycle) stopCheckers() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	checkers := l.checkers
+	l.checkers = nil
+	l.mu.Unlock()
+	for _, checker := range checkers {
+		checker.stop()
+	}
+}
+
+// closeIdleConnections it will close idle connections of transport,
+// connections of http.DefaultTransport are shared, so they are kept
+func (t *transport) closeIdleConnections() {
+	if t != nil {
+		t.roundTripper().(*http.Transport).CloseIdleConnections()
+	}
+}
@@ -0,0 +1,80 @@
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newSlowServer(delay time.Duration, received chan<- struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received <- struct{}{}
+		time.Sleep(delay)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[1]}`))
+	}))
+}
+
+func TestCloseWaitRequestsInFlight(t *testing.T) {
+	received := make(chan struct{}, 1)
+	var finished int32
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received <- struct{}{}
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[1]}`))
+	}))
+	defer ts.Close()
+	apicall := NewApiCall(WithBaseUrl(ts.URL))
+
+	responses := make(chan *BaseStandard, 1)
+	go func() {
+		response, _ := apicall.Send("GET", "/", nil)
+		responses <- response
+	}()
+	<-received
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := apicall.Close(ctx)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&finished), "Close returned before request in flight finished")
+	select {
+	case response := <-responses:
+		assert.True(t, response.IsOk())
+	case <-time.After(time.Second):
+		t.Error("request in flight never returned")
+	}
+}
+
+func TestCloseGiveUpWhenContextIsDone(t *testing.T) {
+	received := make(chan struct{}, 1)
+	ts := newSlowServer(200*time.Millisecond, received)
+	defer ts.Close()
+	apicall := NewApiCall(WithBaseUrl(ts.URL))
+
+	go func() {
+		_, _ = apicall.Send("GET", "/", nil)
+	}()
+	<-received
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, apicall.Close(ctx))
+}
+
+func TestSendAfterClose(t *testing.T) {
+	apicall := NewApiCall(WithBaseUrl("http://localhost"), WithMinTLSVersion(tls.VersionTLS12))
+	assert.Nil(t, apicall.Close(context.Background()))
+
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, response)
+	assert.Equal(t, ErrClosed, err)
+}
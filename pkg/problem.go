@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// Problem hold a RFC 7807 problem detail,
+// it is available on BaseStandard.Problem when server
+// respond with application/problem+json
+type Problem struct {
+	// Type is a URI reference which identify problem type
+	Type string `json:"type"`
+	// Title is a short summary of problem type
+	Title string `json:"title"`
+	// Status is http status code generated by server
+	Status int `json:"status"`
+	// Detail is explanation specific to this occurrence of problem
+	Detail string `json:"detail"`
+	// Instance is a URI reference which identify this occurrence of problem
+	Instance string `json:"instance"`
+	// Extensions hold any other member of problem
+	Extensions map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON it will decode standard members into fields
+// and keep everything else in Problem.Extensions
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	type problem Problem
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, (*problem)(p)); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, key)
+	}
+	p.Extensions = nil
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+	return nil
+}
+
+// Code return type of problem, or status when type is about:blank
+func (p *Problem) Code() string {
+	if (p.Type == "" || p.Type == "about:blank") && p.Status > 0 {
+		return strconv.Itoa(p.Status)
+	}
+	if p.Type == "" {
+		return "about:blank"
+	}
+	return p.Type
+}
+
+// Metas it will map problem into a slice of Meta, first one is
+// problem itself followed by one for each extension member
+func (p *Problem) Metas() ItemsMeta {
+	description := joinDescription(p.Title, p.Detail)
+	if p.Instance != "" {
+		description += " (" + p.Instance + ")"
+	}
+	metas := ItemsMeta{{Code: p.Code(), Description: description}}
+
+	keys := make([]string, 0, len(p.Extensions))
+	for key := range p.Extensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		metas = append(metas, extensionMetas(key, p.Extensions[key])...)
+	}
+	return metas
+}
+
+// extensionMetas it will map an extension member into Meta, a list
+// of {"name":..., "reason":...} as used by invalid-params is mapped
+// into one Meta for each item
+func extensionMetas(key string, value json.RawMessage) ItemsMeta {
+	var params []struct {
+		Name   string `json:"name"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(value, &params); err == nil && len(params) > 0 {
+		metas := make(ItemsMeta, 0, len(params))
+		for _, param := range params {
+			metas = append(metas, Meta{Code: key + "." + param.Name, Description: param.Reason})
+		}
+		return metas
+	}
+
+	var description string
+	if err := json.Unmarshal(value, &description); err != nil {
+		description = string(value)
+	}
+	return ItemsMeta{{Code: key, Description: description}}
+}
+
+type problemEnvelope struct{}
+
+func (problemEnvelope) Decode(raw []byte, baseResponse *BaseStandard) error {
+	problem := new(Problem)
+	if err := json.Unmarshal(raw, problem); err != nil {
+		return err
+	}
+
+	baseResponse.Problem = problem
+	baseResponse.Errors.Items = append(baseResponse.Errors.Items, problem.Metas()...)
+	return nil
+}
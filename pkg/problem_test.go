@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanParseProblem(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+		writer.WriteHeader(http.StatusBadRequest)
+		_, _ = writer.Write([]byte(`{"type":"https://example.net/validation-error","title":"Your request parameters didn't validate.","status":400,"detail":"Age must be positive.","instance":"/users/1","balance":30,"invalid-params":[{"name":"age","reason":"must be a positive integer"},{"name":"color","reason":"must be 'green', 'red' or 'blue'"}]}`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("POST", "/users", nil)
+
+	assert.Nil(t, err)
+	assert.False(t, response.IsOk())
+	assert.Equal(t, 400, response.StatusCode)
+	assert.NotNil(t, response.Problem)
+	assert.Equal(t, "https://example.net/validation-error", response.Problem.Type)
+	assert.Equal(t, "Your request parameters didn't validate.", response.Problem.Title)
+	assert.Equal(t, 400, response.Problem.Status)
+	assert.Equal(t, "Age must be positive.", response.Problem.Detail)
+	assert.Equal(t, "/users/1", response.Problem.Instance)
+	assert.Equal(t, "30", string(response.Problem.Extensions["balance"]))
+	assert.Equal(t, ItemsMeta{
+		{"https://example.net/validation-error", "Your request parameters didn't validate.: Age must be positive. (/users/1)"},
+		{"balance", "30"},
+		{"invalid-params.age", "must be a positive integer"},
+		{"invalid-params.color", "must be 'green', 'red' or 'blue'"},
+	}, response.AuditInfo.Errors.Items)
+}
+
+func TestProblemCode(t *testing.T) {
+	tables := []struct {
+		problem Problem
+		code    string
+	}{
+		{Problem{Type: "https://example.net/out-of-credit", Status: 403}, "https://example.net/out-of-credit"},
+		{Problem{Type: "about:blank", Status: 404}, "404"},
+		{Problem{Status: 500}, "500"},
+		{Problem{}, "about:blank"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.code, func(t *testing.T) {
+			assert.Equal(t, table.code, table.problem.Code())
+		})
+	}
+}
+
+func TestProblemWithoutExtensions(t *testing.T) {
+	problem := new(Problem)
+	err := json.Unmarshal([]byte(`{"title":"Not Found","status":404}`), problem)
+
+	assert.Nil(t, err)
+	assert.Nil(t, problem.Extensions)
+	assert.Equal(t, ItemsMeta{{"404", "Not Found"}}, problem.Metas())
+}
+
+func TestProblemExtensionString(t *testing.T) {
+	problem := new(Problem)
+	err := json.Unmarshal([]byte(`{"title":"Forbidden","trace":"abc-123"}`), problem)
+
+	assert.Nil(t, err)
+	assert.Equal(t, ItemsMeta{{"about:blank", "Forbidden"}, {"trace", "abc-123"}}, problem.Metas())
+}
@@ -0,0 +1,200 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit configure token bucket used by WithRateLimit
+type RateLimit struct {
+	// PerSecond is how many requests are allowed each second,
+	// when zero only Adaptive pauses are applied
+	PerSecond float64
+	// Burst is how many requests can be sent at once
+	Burst int
+	// PerHost give each host its own bucket,
+	// instead of one bucket for the whole client
+	PerHost bool
+	// Adaptive pause requests when a response report rate limit was reached,
+	// through Retry-After or X-RateLimit-Remaining and X-RateLimit-Reset headers
+	Adaptive bool
+}
+
+type rateLimiter struct {
+	limit   RateLimit
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// WithRateLimit it will block Send until a token is available,
+// up to context deadline
+func WithRateLimit(limit RateLimit) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && (limit.PerSecond < 0 || limit.Burst < 0) {
+			a.addError(errors.New("rate limit: PerSecond and Burst must not be negative"))
+			return &a
+		}
+		if a.strict && limit.PerSecond == 0 && !limit.Adaptive {
+			a.addError(errors.New("rate limit: PerSecond must be positive unless it is Adaptive"))
+			return &a
+		}
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		a.rateLimiter = &rateLimiter{limit: limit, buckets: make(map[string]*tokenBucket)}
+		return &a
+	}
+}
+
+// wait it will block until a token of host bucket is available, if token
+// isn't available before ctx deadline it will return immediately
+func (l *rateLimiter) wait(ctx context.Context, host string) error {
+	if l == nil {
+		return nil
+	}
+	bucket := l.bucket(host)
+	delay := bucket.reserve(time.Now(), l.limit)
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		bucket.cancel(l.limit)
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		bucket.cancel(l.limit)
+		return ctx.Err()
+	}
+}
+
+// observe it will pause host bucket when response report
+// that rate limit was reached
+func (l *rateLimiter) observe(host string, response *http.Response) {
+	if l == nil || !l.limit.Adaptive {
+		return
+	}
+	now := time.Now()
+	until, ok := retryAfter(response.Header.Get("Retry-After"), now)
+	if !ok && response.Header.Get("X-RateLimit-Remaining") == "0" {
+		until, ok = rateLimitReset(response.Header.Get("X-RateLimit-Reset"), now)
+	}
+	if !ok {
+		return
+	}
+
+	bucket := l.bucket(host)
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+	if until.After(bucket.pausedUntil) {
+		bucket.pausedUntil = until
+	}
+}
+
+func (l *rateLimiter) bucket(host string) *tokenBucket {
+	if !l.limit.PerHost {
+		host = ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.limit.Burst)}
+		l.buckets[host] = bucket
+	}
+	return bucket
+}
+
+// reserve it will take a token and return how long
+// caller must wait before using it
+func (b *tokenBucket) reserve(now time.Time, limit RateLimit) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var delay time.Duration
+	if b.pausedUntil.After(now) {
+		delay = b.pausedUntil.Sub(now)
+	}
+	if limit.PerSecond <= 0 {
+		return delay
+	}
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * limit.PerSecond
+	}
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens < 0 {
+		if wait := time.Duration(-b.tokens / limit.PerSecond * float64(time.Second)); wait > delay {
+			delay = wait
+		}
+	}
+	return delay
+}
+
+// cancel it will give back a token which was reserved but not used
+func (b *tokenBucket) cancel(limit RateLimit) {
+	if limit.PerSecond <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+}
+
+// retryAfter parse Retry-After header, which can be
+// a number of seconds or a http date
+func retryAfter(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+// rateLimitReset parse X-RateLimit-Reset header, which can be
+// a unix timestamp or a number of seconds
+func rateLimitReset(value string, now time.Time) (time.Time, bool) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if seconds > 1000000000 {
+		return time.Unix(seconds, 0), true
+	}
+	return now.Add(time.Duration(seconds) * time.Second), true
+}
+
+// hostOf return host of rawUrl, or rawUrl itself when it can't be parsed
+func hostOf(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return u.Host
+}
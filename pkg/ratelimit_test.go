@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newRateLimitServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[{"echo":"Hello World"}]}`))
+	}))
+}
+
+func TestRateLimitBlockAfterBurst(t *testing.T) {
+	ts := newRateLimitServer()
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRateLimit(RateLimit{PerSecond: 10, Burst: 2}),
+	)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		response, err := apicall.Send("GET", "/", nil)
+		assert.Nil(t, err)
+		assert.True(t, response.IsOk())
+	}
+
+	assert.True(t, time.Since(start) >= 80*time.Millisecond, "third request must wait for a token")
+}
+
+func TestRateLimitReturnTimeoutWhenDeadlineIsTooShort(t *testing.T) {
+	ts := newRateLimitServer()
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithTimeout(100*time.Millisecond),
+		WithRateLimit(RateLimit{PerSecond: 1, Burst: 1}),
+	)
+	_, _ = apicall.Send("GET", "/", nil)
+	start := time.Now()
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < 50*time.Millisecond, "it must not wait when token isn't available before deadline")
+	assert.False(t, response.IsOk())
+	assert.Equal(t, "1", response.AuditInfo.Errors.Items[0].Code)
+	assert.Equal(t, "Timeout", response.AuditInfo.Errors.Items[0].Description)
+}
+
+func TestRateLimitPerHost(t *testing.T) {
+	first := newRateLimitServer()
+	defer first.Close()
+	second := newRateLimitServer()
+	defer second.Close()
+
+	apicall := NewApiCall(
+		WithTimeout(100*time.Millisecond),
+		WithRateLimit(RateLimit{PerSecond: 1, Burst: 1, PerHost: true}),
+	)
+	firstResponse, _ := apicall.Send("GET", first.URL, nil)
+	secondResponse, _ := apicall.Send("GET", second.URL, nil)
+	limitedResponse, _ := apicall.Send("GET", first.URL, nil)
+
+	assert.True(t, firstResponse.IsOk())
+	assert.True(t, secondResponse.IsOk())
+	assert.False(t, limitedResponse.IsOk())
+}
+
+func TestRateLimitAdaptive(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		calls++
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Retry-After", "2")
+		writer.WriteHeader(http.StatusTooManyRequests)
+		_, _ = writer.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithTimeout(100*time.Millisecond),
+		WithRateLimit(RateLimit{Adaptive: true}),
+	)
+	_, _ = apicall.Send("GET", "/", nil)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "Timeout", response.AuditInfo.Errors.Items[0].Description)
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	now := time.Now()
+	limit := RateLimit{PerSecond: 10, Burst: 1}
+	bucket := &tokenBucket{tokens: 1}
+
+	assert.Equal(t, time.Duration(0), bucket.reserve(now, limit))
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(now, limit))
+	bucket.cancel(limit)
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(now, limit))
+	assert.Equal(t, time.Duration(0), bucket.reserve(now.Add(time.Second), limit))
+
+	bucket.pausedUntil = now.Add(3 * time.Second)
+	assert.Equal(t, time.Second, bucket.reserve(now.Add(2*time.Second), limit))
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tables := []struct {
+		value string
+		until time.Time
+		ok    bool
+	}{
+		{"", time.Time{}, false},
+		{"30", now.Add(30 * time.Second), true},
+		{"Wed, 01 Jan 2020 00:01:00 GMT", now.Add(time.Minute), true},
+		{"soon", time.Time{}, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.value, func(t *testing.T) {
+			until, ok := retryAfter(table.value, now)
+			assert.Equal(t, table.ok, ok)
+			assert.True(t, table.until.Equal(until))
+		})
+	}
+}
+
+func TestRateLimitReset(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	delta, ok := rateLimitReset("60", now)
+	assert.True(t, ok)
+	assert.True(t, now.Add(time.Minute).Equal(delta))
+
+	timestamp, ok := rateLimitReset("1577836860", now)
+	assert.True(t, ok)
+	assert.True(t, now.Add(time.Minute).Equal(timestamp))
+
+	_, ok = rateLimitReset("", now)
+	assert.False(t, ok)
+}
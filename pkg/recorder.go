@@ -0,0 +1,274 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+)
+
+// RecorderMode tell if Recorder must record or replay requests
+type RecorderMode int
+
+const (
+	// ModeRecord send requests and record them with their responses
+	ModeRecord RecorderMode = iota
+	// ModeReplay respond with recorded responses without sending requests
+	ModeReplay
+)
+
+// ErrUnmatchedRequest is returned in replay mode when
+// no recorded request match request sent
+var ErrUnmatchedRequest = errors.New("no recorded request match")
+
+// RecordedRequest is a request stored in a cassette
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    Body        `json:"body"`
+}
+
+// RecordedResponse is a response stored in a cassette
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Headers    http.Header `json:"headers"`
+	Body       Body        `json:"body"`
+}
+
+// Interaction is a request and its response stored in a cassette
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Body is a request or response body, it is saved as
+// string when it is valid utf8, otherwise as base64
+type Body []byte
+
+// MarshalJSON it will encode body as string or base64
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON it will decode body saved as string or base64
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var encoded map[string]string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded["base64"])
+	*b = decoded
+	return err
+}
+
+// Recorder record requests and responses into a cassette file
+// or replay them, so tests can run without a server
+type Recorder struct {
+	// Path of cassette file, in json
+	Path string
+	// Mode tell if requests are recorded or replayed
+	Mode RecorderMode
+	// RedactHeaders are replaced by REDACTED before recording,
+	// by default Authorization is redacted
+	RedactHeaders []string
+	// RedactBody can change request and response bodies before recording,
+	// it is also applied to request body before matching
+	RedactBody func(body []byte) []byte
+	// Match tell if a recorded request match request sent,
+	// by default method, url and body must be equal
+	Match func(request RecordedRequest, recorded RecordedRequest) bool
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// NewRecorder it will create a Recorder, in replay mode
+// cassette of path is loaded
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{Path: path, Mode: mode, RedactHeaders: []string{"Authorization"}}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	binary, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+	if err = json.Unmarshal(binary, &cassette); err != nil {
+		return nil, err
+	}
+	r.interactions = cassette.Interactions
+	return r, nil
+}
+
+// WithRecorder it will record or replay every request with recorder
+func WithRecorder(recorder *Recorder) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && recorder == nil {
+			a.addError(errors.New("recorder: must not be nil"))
+			return &a
+		}
+		a.recorder = recorder
+		return &a
+	}
+}
+
+// Save it will write recorded interactions into cassette file
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	binary, err := json.MarshalIndent(map[string][]Interaction{"interactions": r.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Path, binary, 0644)
+}
+
+// Interactions return every interaction recorded or loaded
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// recorderTransport record or replay requests sent through next
+type recorderTransport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *recorderTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(request.Body)
+		_ = request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	recorded := t.recorder.redactRequest(request, body)
+
+	if t.recorder.Mode == ModeReplay {
+		return t.recorder.replay(request, recorded)
+	}
+
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	t.recorder.mu.Lock()
+	defer t.recorder.mu.Unlock()
+	t.recorder.interactions = append(t.recorder.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: response.StatusCode,
+			Headers:    t.recorder.redactHeaders(response.Header),
+			Body:       t.recorder.redactBody(responseBody),
+		},
+	})
+	return response, nil
+}
+
+// replay it will respond with first recorded response, not yet
+// replayed, which request match
+func (r *Recorder) replay(request *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	match := r.Match
+	if match == nil {
+		match = matchRequest
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Mode can be changed after recording, so interactions
+	// may have been added since last replay
+	if len(r.replayed) < len(r.interactions) {
+		r.replayed = append(r.replayed, make([]bool, len(r.interactions)-len(r.replayed))...)
+	}
+	for index, interaction := range r.interactions {
+		if r.replayed[index] || !match(recorded, interaction.Request) {
+			continue
+		}
+		r.replayed[index] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Headers.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       request,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrUnmatchedRequest, recorded.Method, recorded.URL)
+}
+
+// unmatchedRequestMeta describe err when it is ErrUnmatchedRequest,
+// so a replay failure tell which request wasn't recorded
+func unmatchedRequestMeta(err error) (Meta, bool) {
+	if !errors.Is(err, ErrUnmatchedRequest) {
+		return Meta{}, false
+	}
+	return Meta{Code: "4", Description: err.Error()}, true
+}
+
+func (r *Recorder) redactRequest(request *http.Request, body []byte) RecordedRequest {
+	return RecordedRequest{
+		Method:  request.Method,
+		URL:     request.URL.String(),
+		Headers: r.redactHeaders(request.Header),
+		Body:    r.redactBody(body),
+	}
+}
+
+func (r *Recorder) redactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for _, header := range r.RedactHeaders {
+		if redacted.Get(header) != "" {
+			redacted.Set(header, "REDACTED")
+		}
+	}
+	return redacted
+}
+
+func (r *Recorder) redactBody(body []byte) Body {
+	if r.RedactBody != nil {
+		return r.RedactBody(body)
+	}
+	return body
+}
+
+// matchRequest it will match method, url and body
+func matchRequest(request RecordedRequest, recorded RecordedRequest) bool {
+	return request.Method == recorded.Method &&
+		request.URL == recorded.URL &&
+		bytes.Equal(request.Body, recorded.Body)
+}
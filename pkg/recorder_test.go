@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCassettePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "apicall")
+	assert.Nil(t, err)
+	return filepath.Join(dir, "cassette.json"), func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path, clean := newCassettePath(t)
+	defer clean()
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("X-Request-Body", string(body))
+		writer.WriteHeader(http.StatusCreated)
+		_, _ = writer.Write([]byte(`{"items":[{"echo":"Hello World"}]}`))
+	}))
+
+	recorder, err := NewRecorder(path, ModeRecord)
+	assert.Nil(t, err)
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithAuthentication("jonathan", "12345678"),
+		WithRecorder(recorder),
+	)
+	recorded, err := apicall.Send("POST", "/users", strings.NewReader(`{"name":"Jonathan"}`))
+	assert.Nil(t, err)
+	assert.True(t, recorded.IsOk())
+	assert.Nil(t, recorder.Save())
+	ts.Close()
+
+	cassette, _ := ioutil.ReadFile(path)
+	assert.Contains(t, string(cassette), "REDACTED")
+	assert.NotContains(t, string(cassette), "Basic")
+
+	replayer, err := NewRecorder(path, ModeReplay)
+	assert.Nil(t, err)
+	apicall = NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRecorder(replayer),
+	)
+	replayed, err := apicall.Send("POST", "/users", strings.NewReader(`{"name":"Jonathan"}`))
+
+	assert.Nil(t, err)
+	assert.True(t, replayed.IsOk())
+	assert.Equal(t, 201, replayed.StatusCode)
+	assert.Equal(t, "201 Created", replayed.Status)
+	assert.Equal(t, `{"name":"Jonathan"}`, replayed.Header.Get("X-Request-Body"))
+	assert.Equal(t, string(*recorded.Items), string(*replayed.Items))
+}
+
+func TestRecordThenReplayWithSameRecorder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[{"echo":"Hello World"}]}`))
+	}))
+	recorder := &Recorder{}
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRecorder(recorder),
+	)
+	recorded, err := apicall.Send("GET", "/users", nil)
+	assert.Nil(t, err)
+	assert.True(t, recorded.IsOk())
+	ts.Close()
+
+	recorder.Mode = ModeReplay
+	replayed, err := apicall.Send("GET", "/users", nil)
+	again, _ := apicall.Send("GET", "/users", nil)
+
+	assert.Nil(t, err)
+	if assert.True(t, replayed.IsOk()) {
+		assert.Equal(t, string(*recorded.Items), string(*replayed.Items))
+	}
+	assert.False(t, again.IsOk())
+}
+
+func TestReplayFailOnUnmatchedRequest(t *testing.T) {
+	path, clean := newCassettePath(t)
+	defer clean()
+	cassette := `{"interactions":[{"request":{"method":"GET","url":"http://localhost/users","body":""},"response":{"statusCode":200,"body":"{\"items\":[1]}"}}]}`
+	assert.Nil(t, ioutil.WriteFile(path, []byte(cassette), 0644))
+
+	replayer, err := NewRecorder(path, ModeReplay)
+	assert.Nil(t, err)
+	apicall := NewApiCall(
+		WithBaseUrl("http://localhost"),
+		WithRecorder(replayer),
+	)
+	first, _ := apicall.Send("GET", "/users", nil)
+	second, _ := apicall.Send("GET", "/users", nil)
+	other, _ := apicall.Send("GET", "/other", nil)
+
+	assert.True(t, first.IsOk())
+	assert.False(t, second.IsOk())
+	assert.Contains(t, second.AuditInfo.Errors.String(), "no recorded request match: GET http://localhost/users")
+	assert.Contains(t, other.AuditInfo.Errors.String(), "no recorded request match: GET http://localhost/other")
+}
+
+func TestRecorderRedactBodyAndCustomMatch(t *testing.T) {
+	path, clean := newCassettePath(t)
+	defer clean()
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[{"token":"secret"}]}`))
+	}))
+	redact := func(body []byte) []byte {
+		return bytes.Replace(body, []byte("secret"), []byte("******"), -1)
+	}
+
+	recorder, _ := NewRecorder(path, ModeRecord)
+	recorder.RedactBody = redact
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRecorder(recorder),
+	)
+	_, _ = apicall.Send("POST", "/login", strings.NewReader(`{"password":"secret"}`))
+	assert.Nil(t, recorder.Save())
+	ts.Close()
+
+	interactions := recorder.Interactions()
+	assert.Len(t, interactions, 1)
+	assert.Equal(t, `{"password":"******"}`, string(interactions[0].Request.Body))
+	assert.Equal(t, `{"items":[{"token":"******"}]}`, string(interactions[0].Response.Body))
+
+	replayer, _ := NewRecorder(path, ModeReplay)
+	replayer.Match = func(request RecordedRequest, recorded RecordedRequest) bool {
+		return request.Method == recorded.Method
+	}
+	apicall = NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRecorder(replayer),
+	)
+	response, _ := apicall.Send("POST", "/another-login", strings.NewReader(`{"password":"other"}`))
+
+	assert.True(t, response.IsOk())
+	assert.Equal(t, `[{"token":"******"}]`, string(*response.Items))
+}
+
+func TestRecorderWithoutCassette(t *testing.T) {
+	recorder, err := NewRecorder("does-not-exist.json", ModeReplay)
+
+	assert.Nil(t, recorder)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBodyEncoding(t *testing.T) {
+	text, _ := json.Marshal(Body("Hello World"))
+	binary, _ := json.Marshal(Body{0xff, 0xfe})
+	var decoded Body
+
+	assert.Equal(t, `"Hello World"`, string(text))
+	assert.Equal(t, `{"base64":"//4="}`, string(binary))
+	assert.Nil(t, json.Unmarshal(binary, &decoded))
+	assert.Equal(t, Body{0xff, 0xfe}, decoded)
+}
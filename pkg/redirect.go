@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrTooManyRedirects is returned when a request is
+// redirected more than RedirectPolicy.MaxRedirects
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// ErrCrossHostRedirect is returned when a request is redirected
+// to another host and RedirectPolicy.CrossHost is false
+var ErrCrossHostRedirect = errors.New("redirect to another host not allowed")
+
+// RedirectPolicy control how redirects are followed
+type RedirectPolicy struct {
+	// MaxRedirects is maximum number of redirects followed, when
+	// zero no redirect is followed and redirect response is returned
+	MaxRedirects int
+	// CrossHost allow redirects to another host
+	CrossHost bool
+	// KeepAuth keep Authorization header when redirected to
+	// another host, by default it is stripped
+	KeepAuth bool
+}
+
+// defaultRedirectPolicy is used when none is given,
+// same as http.Client default
+var defaultRedirectPolicy = RedirectPolicy{MaxRedirects: 10, CrossHost: true}
+
+// WithRedirectPolicy it will follow redirects according policy
+func WithRedirectPolicy(policy RedirectPolicy) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && policy.MaxRedirects < 0 {
+			a.addError(fmt.Errorf("redirect: MaxRedirects must not be negative, got %d", policy.MaxRedirects))
+			return &a
+		}
+		a.Redirect = &policy
+		return &a
+	}
+}
+
+// checkRedirect it will be used as http.Client.CheckRedirect,
+// via hold requests already made, oldest first
+func (p RedirectPolicy) checkRedirect(request *http.Request, via []*http.Request) error {
+	if p.MaxRedirects == 0 {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > p.MaxRedirects {
+		return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, p.MaxRedirects)
+	}
+	first := via[0]
+	if request.URL.Host == first.URL.Host {
+		return nil
+	}
+	if !p.CrossHost {
+		return fmt.Errorf("%w: %s", ErrCrossHostRedirect, request.URL.Host)
+	}
+	if p.KeepAuth {
+		if auth := first.Header.Get("Authorization"); auth != "" {
+			request.Header.Set("Authorization", auth)
+		}
+		return nil
+	}
+	request.Header.Del("Authorization")
+	return nil
+}
+
+// redirects return every url response was redirected to, by order
+func redirects(response *http.Response) []string {
+	var urls []string
+	for request := response.Request; request != nil && request.Response != nil; request = request.Response.Request {
+		urls = append([]string{request.URL.String()}, urls...)
+	}
+	return urls
+}
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+)
+
+// ResolveFunc return addresses of host, each address
+// is an ip or an ip with port, e.g. 10.0.0.1:8080
+type ResolveFunc func(ctx context.Context, host string) ([]string, error)
+
+// Resolver configure how hosts are resolved by WithResolver
+type Resolver struct {
+	// Hosts map a host to its addresses, e.g.
+	// "api.example.com": {"10.0.0.1", "10.0.0.2"}
+	Hosts map[string][]string
+	// Resolve is called for hosts not in Hosts,
+	// when nil they are resolved by system
+	Resolve ResolveFunc
+	// RoundRobin start each connection at next address,
+	// otherwise addresses are tried by order
+	RoundRobin bool
+}
+
+// resolver dial addresses of Resolver, trying next
+// address when a connection fail
+type resolver struct {
+	Resolver
+	next uint32
+}
+
+// WithResolver it will resolve hosts with resolver instead of system,
+// on connection failure next address of host is tried
+func WithResolver(resolver Resolver) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && len(resolver.Hosts) == 0 && resolver.Resolve == nil {
+			a.addError(errors.New("resolver: Hosts or Resolve is required"))
+			return &a
+		}
+		for host, addresses := range resolver.Hosts {
+			for _, address := range addresses {
+				if a.strict && !validAddress(address) {
+					a.addError(fmt.Errorf("resolver: invalid address %q of %s", address, host))
+					return &a
+				}
+			}
+		}
+		a.transport = a.transport.with(func(config *transportConfig) {
+			config.resolver = newResolver(resolver)
+		})
+		return &a
+	}
+}
+
+func newResolver(r Resolver) *resolver {
+	return &resolver{Resolver: r}
+}
+
+// validAddress return true when address is an ip or an ip with port
+func validAddress(address string) bool {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	return net.ParseIP(address) != nil
+}
+
+// addresses return addresses of host, nil
+// when host must be resolved by system
+func (r *resolver) addresses(ctx context.Context, host string) ([]string, error) {
+	if addresses, ok := r.Hosts[host]; ok {
+		return addresses, nil
+	}
+	if r.Resolve != nil {
+		return r.Resolve(ctx, host)
+	}
+	return nil, nil
+}
+
+// dial return a DialFunc which connect with dial to addresses of host
+func (r *resolver) dial(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return dial(ctx, network, address)
+		}
+		addresses, err := r.addresses(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addresses) == 0 {
+			return dial(ctx, network, address)
+		}
+
+		start := 0
+		if r.RoundRobin {
+			start = int(atomic.AddUint32(&r.next, 1)-1) % len(addresses)
+		}
+		for i := range addresses {
+			target := addresses[(start+i)%len(addresses)]
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				target = net.JoinHostPort(target, port)
+			}
+			var conn net.Conn
+			conn, err = dial(ctx, network, target)
+			if err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, err
+	}
+}
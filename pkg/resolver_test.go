@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newNamedServer(name string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":{"server":"` + name + `","host":"` + request.Host + `"}}`))
+	}))
+	ts.Config.SetKeepAlivesEnabled(false)
+	return ts
+}
+
+// closedAddress return an address where nobody listen
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	_ = listener.Close()
+	return address
+}
+
+func TestStaticResolver(t *testing.T) {
+	ts := newNamedServer("a")
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	apicall := NewApiCall(
+		WithBaseUrl("http://api.staging:"+port),
+		WithResolver(Resolver{Hosts: map[string][]string{"api.staging": {"127.0.0.1"}}}),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, `{"server":"a","host":"api.staging:`+port+`"}`, string(*response.Items))
+}
+
+func TestResolverRoundRobinAndFailover(t *testing.T) {
+	a := newNamedServer("a")
+	defer a.Close()
+	b := newNamedServer("b")
+	defer b.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl("http://api.staging"),
+		WithResolver(Resolver{
+			Hosts:      map[string][]string{"api.staging": {a.Listener.Addr().String(), closedAddress(t), b.Listener.Addr().String()}},
+			RoundRobin: true,
+		}),
+	)
+	var servers []string
+	for i := 0; i < 3; i++ {
+		response, _ := apicall.Send("GET", "/", nil)
+		var items struct {
+			Server string `json:"server"`
+		}
+		assert.Nil(t, response.GetItems(&items))
+		servers = append(servers, items.Server)
+	}
+
+	assert.Equal(t, []string{"a", "b", "b"}, servers)
+}
+
+func TestResolverFunction(t *testing.T) {
+	ts := newNamedServer("a")
+	defer ts.Close()
+	var resolved []string
+
+	apicall := NewApiCall(
+		WithResolver(Resolver{Resolve: func(ctx context.Context, host string) ([]string, error) {
+			resolved = append(resolved, host)
+			if host != "api.staging" {
+				return nil, errors.New("unknown host")
+			}
+			return []string{ts.Listener.Addr().String()}, nil
+		}}),
+	)
+	response, _ := apicall.Send("GET", "http://api.staging", nil)
+	failed, _ := apicall.Send("GET", "http://other.staging", nil)
+
+	assert.True(t, response.IsOk())
+	assert.False(t, failed.IsOk())
+	assert.Contains(t, failed.Errors.String(), "unknown host")
+	assert.Equal(t, []string{"api.staging", "other.staging"}, resolved)
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"reflect"
 	"strings"
 	"time"
 )
@@ -14,8 +13,8 @@ import (
 // Meta hold information of AuditInfo
 // for keys Errors, Info and Warning
 type Meta struct {
-	Code        string `json:"code"`
-	Description string `json:"description"`
+	Code        string `json:"code" xml:"code"`
+	Description string `json:"description" xml:"description"`
 }
 
 // ItemsMeta is slice of Meta
@@ -23,7 +22,7 @@ type ItemsMeta []Meta
 
 // Items hold information for each key
 type Items struct {
-	Items ItemsMeta `json:"items"`
+	Items ItemsMeta `json:"items" xml:"items"`
 }
 
 // String it will format into string from Slice of Meta
@@ -39,23 +38,35 @@ func (i Items) String() string {
 // request/response from server side
 type AuditInfo struct {
 	// Duration of request
-	Duration float64 `json:"duration"`
+	Duration float64 `json:"duration" xml:"duration"`
 	// Timestamp when the request started
-	Timestamp time.Time `json:"timestamp"`
+	Timestamp time.Time `json:"timestamp" xml:"timestamp"`
 	// Host is hostname of made request
-	Host string `json:"host"`
+	Host string `json:"host" xml:"host"`
 	// ClientIP who made request
-	ClientIP string `json:"clientIP"`
+	ClientIP string `json:"clientIP" xml:"clientIP"`
 	// Ok if we got success request
-	Ok bool `json:"ok"`
+	Ok bool `json:"ok" xml:"ok"`
 	// StatusCode of result
-	StatusCode int `json:"statusCode"`
+	StatusCode int `json:"statusCode" xml:"statusCode"`
 	// OperationId is a random string for logging purpose
-	OperationId string `json:"operationId"`
-	Errors      Items  `json:"errors"`
-	Info        Items  `json:"info"`
-	Warning     Items  `json:"warning"`
-	Total       int64  `json:"total"`
+	OperationId string `json:"operationId" xml:"operationId"`
+	Errors      Items  `json:"errors" xml:"errors"`
+	Info        Items  `json:"info" xml:"info"`
+	Warning     Items  `json:"warning" xml:"warning"`
+	Total       int64  `json:"total" xml:"total"`
+	// RequestSize is size of request body before compression
+	RequestSize int64 `json:"requestSize" xml:"requestSize"`
+	// RequestCompressedSize is size of request body sent, when compressed
+	RequestCompressedSize int64 `json:"requestCompressedSize" xml:"requestCompressedSize"`
+	// ResponseSize is size of response body after decompression
+	ResponseSize int64 `json:"responseSize" xml:"responseSize"`
+	// ResponseCompressedSize is size of response body received, when compressed
+	ResponseCompressedSize int64 `json:"responseCompressedSize" xml:"responseCompressedSize"`
+	// Redirects is every url request was redirected to, by order
+	Redirects []string `json:"redirects" xml:"redirects"`
+	// Endpoint is base url request was sent to, when there are many
+	Endpoint string `json:"endpoint" xml:"endpoint"`
 }
 
 // BaseStandard it's ao final response
@@ -64,13 +75,39 @@ type BaseStandard struct {
 	Items             *json.RawMessage `json:"items"`
 	AuditInfo         `json:"auditInfo"`
 	InterfaceSettings interface{} `json:"interfaceSettings"`
+	// Metadata hold keys of response which aren't items,
+	// when it is decoded by an Envelope other than StandardEnvelope
+	Metadata map[string]json.RawMessage `json:"-"`
+	// Problem hold problem detail when response
+	// is a application/problem+json
+	Problem *Problem `json:"-"`
+	// Status is status line of response, e.g. "200 OK"
+	Status string `json:"-"`
+	// Proto is protocol of response, e.g. "HTTP/1.1"
+	Proto string `json:"-"`
+	// Header hold headers of response
+	Header http.Header `json:"-"`
+	// URL is final url of request, after following redirects
+	URL string `json:"-"`
+	// RawBody is whole response body as received, after decompression,
+	// it is only kept when ApiCall.RawBody is set
+	RawBody []byte `json:"-"`
+	// codec decode items when response isn't json
+	codec Codec
+	// rawItems hold items decoded by codec, which
+	// can't be kept in Items as they aren't json
+	rawItems []byte
 }
 
 // GetItems it transform delayed parsed json into structure provider
 // e.g. response.GetItems(&MyStruct{})
 // MyStruct[0].Foo, etc.
+// When response is xml, items are the whole <items> element
 func (r *BaseStandard) GetItems(structType interface{}) error {
 	items := r.Items
+	if r.codec != nil {
+		return r.codec.Unmarshal(r.rawItems, structType)
+	}
 	return json.Unmarshal(*items, structType)
 }
 
@@ -94,8 +131,12 @@ func (r *BaseStandard) IsOk() bool {
 }
 
 // HasItems it will return true if
-// BaseStandard.Items is not nil and len > 0
+// BaseStandard.Items is not nil and len > 0,
+// numbers and booleans are always considered an item
 func (r *BaseStandard) HasItems() bool {
+	if _, ok := r.codec.(xmlCodec); ok {
+		return hasXMLItems(r.rawItems)
+	}
 	if r.Items == nil {
 		return false
 	}
@@ -105,7 +146,18 @@ func (r *BaseStandard) HasItems() bool {
 		return false
 	}
 
-	return genericItems != nil && reflect.ValueOf(genericItems).Len() > 0
+	switch items := genericItems.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		return len(items) > 0
+	case map[string]interface{}:
+		return len(items) > 0
+	case string:
+		return len(items) > 0
+	}
+
+	return true
 }
 
 func (r *BaseStandard) newOperationId() (string, error) {
@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -34,3 +35,29 @@ func TestGenerateOperationId(t *testing.T) {
 	op, _ := b.newOperationId()
 	assert.Equal(t, "0b00fff8ca0e86cb772c7ef037c6713d", op)
 }
+
+func TestHasItemsWithAnyKindOfJson(t *testing.T) {
+	tables := []struct {
+		items    string
+		expected bool
+	}{
+		{`[{"echo":"Hello World"}]`, true},
+		{`[]`, false},
+		{`{"token":"token"}`, true},
+		{`{}`, false},
+		{`"Hello World"`, true},
+		{`""`, false},
+		{`10`, true},
+		{`false`, true},
+		{`null`, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.items, func(t *testing.T) {
+			items := json.RawMessage(table.items)
+			b := BaseStandard{Items: &items}
+
+			assert.Equal(t, table.expected, b.HasItems())
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// defaultRetryBackoff is RetryPolicy.Backoff of a policy without one
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// RetryPolicy control how failed requests are sent again
+type RetryPolicy struct {
+	// MaxRetries is how many times a request is sent again after
+	// its first attempt, when zero requests aren't retried
+	MaxRetries int
+	// Backoff is wait before first retry, doubled on each
+	// retry, when zero 100 milliseconds is used
+	Backoff time.Duration
+	// MaxBackoff cap wait between retries, including one asked
+	// by Retry-After, when zero it isn't capped
+	MaxBackoff time.Duration
+}
+
+// WithRetry it will send again GET, HEAD, OPTIONS, PUT and DELETE requests
+// which failed to connect or got 429, 502, 503 or 504, waiting between
+// attempts as policy or Retry-After tell, Timeout cover every attempt
+func WithRetry(policy RetryPolicy) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && policy.MaxRetries < 0 {
+			a.addError(fmt.Errorf("retry: MaxRetries must not be negative, got %d", policy.MaxRetries))
+			return &a
+		}
+		if a.strict && (policy.Backoff < 0 || policy.MaxBackoff < 0) {
+			a.addError(errors.New("retry: Backoff and MaxBackoff must not be negative"))
+			return &a
+		}
+		a.retry = &policy
+		return &a
+	}
+}
+
+// do it will call send until it succeed or retries are exhausted,
+// body is read once so each attempt send it whole
+func (p *RetryPolicy) do(ctx context.Context, method string, body io.Reader, send func(body io.Reader) (*http.Response, error)) (*http.Response, error) {
+	if p == nil || p.MaxRetries <= 0 || !idempotent(method) {
+		return send(body)
+	}
+	var binary []byte
+	if body != nil {
+		var err error
+		binary, err = ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			body = bytes.NewReader(binary)
+		}
+		response, err := send(body)
+		if attempt >= p.MaxRetries || ctx.Err() != nil || !retryable(response, err) {
+			return response, err
+		}
+
+		wait := backoff
+		if response != nil {
+			if until, ok := retryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+				wait = time.Until(until)
+			}
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+			_ = response.Body.Close()
+		}
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// idempotent return true when sending method again has no other effect
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable return true when request failed for a reason which may
+// go away, errors of this package are refused by client, not failures
+func retryable(response *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrTooManyRedirects) &&
+			!errors.Is(err, ErrCrossHostRedirect) &&
+			!errors.Is(err, ErrPinMismatch) &&
+			!errors.Is(err, ErrUnmatchedRequest)
+	}
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
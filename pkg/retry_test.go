@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer return a server which respond status to first
+// failures requests, then echo request body, attempts are counted
+func newFlakyServer(status int, failures int64, attempts *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		if atomic.AddInt64(attempts, 1) <= failures {
+			writer.Header().Set("Retry-After", "1")
+			writer.WriteHeader(status)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":["` + string(body) + `"]}`))
+	}))
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	var attempts int64
+	ts := newFlakyServer(http.StatusServiceUnavailable, 2, &attempts)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRetry(RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}),
+	)
+	response, err := apicall.Send("PUT", "/users", strings.NewReader(`jonathan`))
+
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+	assert.Equal(t, `["jonathan"]`, string(*response.Items))
+	assert.Equal(t, int64(3), atomic.LoadInt64(&attempts))
+}
+
+func TestRetryGiveUpAfterMaxRetries(t *testing.T) {
+	var attempts int64
+	ts := newFlakyServer(http.StatusTooManyRequests, 5, &attempts)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRetry(RetryPolicy{MaxRetries: 1, MaxBackoff: time.Millisecond}),
+	)
+	response, err := apicall.Send("GET", "/users", nil)
+
+	assert.Nil(t, err)
+	assert.False(t, response.IsOk())
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&attempts))
+}
+
+func TestRetryOnlyIdempotentRequests(t *testing.T) {
+	var attempts int64
+	ts := newFlakyServer(http.StatusServiceUnavailable, 1, &attempts)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithRetry(RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond}),
+	)
+	response, _ := apicall.Send("POST", "/users", strings.NewReader(`jonathan`))
+
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&attempts))
+}
+
+func TestRetryStopWhenTimeoutIsReached(t *testing.T) {
+	var attempts int64
+	ts := newFlakyServer(http.StatusBadGateway, 5, &attempts)
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithTimeout(50*time.Millisecond),
+		WithRetry(RetryPolicy{MaxRetries: 5}),
+	)
+	response, _ := apicall.Send("GET", "/users", nil)
+
+	assert.False(t, response.IsOk())
+	assert.Equal(t, "[1]: Timeout", response.Errors.String())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&attempts))
+}
@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrPinMismatch is returned when no certificate of server
+// has a public key pinned with WithPinnedKeys
+var ErrPinMismatch = errors.New("certificate public key not pinned")
+
+// WithCACert it will trust servers signed by CAs of PEM bundles in paths,
+// besides CAs of system, it replace CAs given by a previous WithCACert
+func WithCACert(paths ...string) Option {
+	return func(a ApiCall) *ApiCall {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, path := range paths {
+			bundle, err := ioutil.ReadFile(path)
+			if err != nil {
+				a.addError(fmt.Errorf("ca certificate: %w", err))
+				return &a
+			}
+			if !pool.AppendCertsFromPEM(bundle) {
+				a.addError(fmt.Errorf("ca certificate: no certificate found in %s", path))
+				return &a
+			}
+		}
+		a.transport = a.transport.with(func(config *transportConfig) {
+			config.tlsConfig().RootCAs = pool
+		})
+		return &a
+	}
+}
+
+// WithClientCert it will present certificate of certFile and keyFile, both
+// in PEM, when server ask for one, files are loaded again when they change
+func WithClientCert(certFile, keyFile string) Option {
+	return func(a ApiCall) *ApiCall {
+		pair := &keyPair{certFile: certFile, keyFile: keyFile}
+		if _, err := pair.load(); err != nil {
+			a.addError(fmt.Errorf("client certificate: %w", err))
+			return &a
+		}
+		a.transport = a.transport.with(func(config *transportConfig) {
+			config.tlsConfig().GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return pair.load()
+			}
+		})
+		return &a
+	}
+}
+
+// WithMinTLSVersion it will refuse servers which don't
+// support version, e.g. tls.VersionTLS12
+func WithMinTLSVersion(version uint16) Option {
+	return func(a ApiCall) *ApiCall {
+		if version < tls.VersionTLS10 || version > tls.VersionTLS13 {
+			a.addError(fmt.Errorf("unknown tls version %#04x", version))
+			return &a
+		}
+		a.transport = a.transport.with(func(config *transportConfig) {
+			config.tlsConfig().MinVersion = version
+		})
+		return &a
+	}
+}
+
+// WithPinnedKeys it will only accept servers which verified certificate
+// chain has a public key pinned, including its root CA, pins are made
+// with SPKIPin, certificates are still verified against trusted CAs
+func WithPinnedKeys(pins ...string) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && len(pins) == 0 {
+			a.addError(errors.New("pinned keys: at least one pin is required"))
+			return &a
+		}
+		pinned := make(map[string]bool, len(pins))
+		for _, pin := range pins {
+			if sum, err := base64.StdEncoding.DecodeString(pin); a.strict && (err != nil || len(sum) != sha256.Size) {
+				a.addError(fmt.Errorf("pinned keys: %q isn't base64 of a SHA-256", pin))
+				return &a
+			}
+			pinned[pin] = true
+		}
+		a.transport = a.transport.with(func(config *transportConfig) {
+			config.tlsConfig().VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+				// chains are only missing when verification is skipped
+				// by InsecureSkipVerify, then certificates sent are used
+				if len(verifiedChains) == 0 {
+					for _, raw := range rawCerts {
+						certificate, err := x509.ParseCertificate(raw)
+						if err != nil {
+							return err
+						}
+						verifiedChains = append(verifiedChains, []*x509.Certificate{certificate})
+					}
+				}
+				for _, chain := range verifiedChains {
+					for _, certificate := range chain {
+						if pinned[SPKIPin(certificate)] {
+							return nil
+						}
+					}
+				}
+				return ErrPinMismatch
+			}
+		})
+		return &a
+	}
+}
+
+// SPKIPin return base64 of SHA-256 of certificate SubjectPublicKeyInfo
+func SPKIPin(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// keyPair is a client certificate loaded
+// again when its files are modified
+type keyPair struct {
+	certFile    string
+	keyFile     string
+	mu          sync.Mutex
+	certModTime time.Time
+	keyModTime  time.Time
+	certificate *tls.Certificate
+}
+
+// load return certificate, loading it again when files were modified
+// since last load, previous certificate is kept while new files are invalid
+func (k *keyPair) load() (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	certInfo, certErr := os.Stat(k.certFile)
+	keyInfo, keyErr := os.Stat(k.keyFile)
+	if certErr == nil && keyErr == nil && k.certificate != nil &&
+		certInfo.ModTime().Equal(k.certModTime) && keyInfo.ModTime().Equal(k.keyModTime) {
+		return k.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		if k.certificate != nil {
+			return k.certificate, nil
+		}
+		return nil, err
+	}
+	k.certificate = &certificate
+	if certErr == nil && keyErr == nil {
+		k.certModTime = certInfo.ModTime()
+		k.keyModTime = keyInfo.ModTime()
+	}
+	return k.certificate, nil
+}
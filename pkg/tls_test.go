@@ -0,0 +1,214 @@
+package pkg
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	tls         tls.Certificate
+}
+
+// newTestCertificate it will generate a certificate for 127.0.0.1
+// signed by parent, or self signed CA when parent is nil
+func newTestCertificate(t *testing.T, name string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.certificate, parent.key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	certificate, err := x509.ParseCertificate(raw)
+	assert.Nil(t, err)
+	return &testCertificate{
+		certificate: certificate,
+		key:         key,
+		tls:         tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key},
+	}
+}
+
+// write it will save certificate and key as PEM files into dir
+func (c *testCertificate) write(t *testing.T, dir string) (string, string) {
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	assert.Nil(t, err)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.certificate.Raw}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func newTLSServer(config *tls.Config) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		client := ""
+		if len(request.TLS.PeerCertificates) > 0 {
+			client = request.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":{"client":"` + client + `"}}`))
+	}))
+	ts.TLS = config
+	ts.Config.SetKeepAlivesEnabled(false)
+	ts.StartTLS()
+	return ts
+}
+
+// newTempDir return a new directory and a function to remove it
+func newTempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "apicall")
+	assert.Nil(t, err)
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestPrivateCA(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	server := newTestCertificate(t, "server", ca)
+	ts := newTLSServer(&tls.Config{Certificates: []tls.Certificate{server.tls}})
+	defer ts.Close()
+	dir, remove := newTempDir(t)
+	defer remove()
+	caFile, _ := ca.write(t, dir)
+
+	untrusted := NewApiCall(WithBaseUrl(ts.URL))
+	trusted := NewApiCall(WithBaseUrl(ts.URL), WithCACert(caFile))
+	failed, _ := untrusted.Send("GET", "/", nil)
+	response, err := trusted.Send("GET", "/", nil)
+
+	assert.False(t, failed.IsOk())
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+}
+
+func TestClientCertificateIsReloaded(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	server := newTestCertificate(t, "server", ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	ts := newTLSServer(&tls.Config{
+		Certificates: []tls.Certificate{server.tls},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	defer ts.Close()
+	dir, remove := newTempDir(t)
+	defer remove()
+	caDir, removeCA := newTempDir(t)
+	defer removeCA()
+	caFile, _ := ca.write(t, caDir)
+	certFile, keyFile := newTestCertificate(t, "first", ca).write(t, dir)
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithCACert(caFile),
+		WithClientCert(certFile, keyFile),
+	)
+	first, _ := apicall.Send("GET", "/", nil)
+	newTestCertificate(t, "second", ca).write(t, dir)
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, later, later))
+	assert.Nil(t, os.Chtimes(keyFile, later, later))
+	second, _ := apicall.Send("GET", "/", nil)
+
+	assert.Equal(t, `{"client":"first"}`, string(*first.Items))
+	assert.Equal(t, `{"client":"second"}`, string(*second.Items))
+}
+
+func TestMinTLSVersion(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	server := newTestCertificate(t, "server", ca)
+	ts := newTLSServer(&tls.Config{Certificates: []tls.Certificate{server.tls}, MaxVersion: tls.VersionTLS12})
+	defer ts.Close()
+	dir, remove := newTempDir(t)
+	defer remove()
+	caFile, _ := ca.write(t, dir)
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithCACert(caFile),
+		WithMinTLSVersion(tls.VersionTLS13),
+	)
+	response, _ := apicall.Send("GET", "/", nil)
+
+	assert.False(t, response.IsOk())
+	assert.Contains(t, response.Errors.String(), "protocol version")
+}
+
+func TestPinnedKeys(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	server := newTestCertificate(t, "server", ca)
+	ts := newTLSServer(&tls.Config{Certificates: []tls.Certificate{server.tls}})
+	defer ts.Close()
+	dir, remove := newTempDir(t)
+	defer remove()
+	caFile, _ := ca.write(t, dir)
+	other := newTestCertificate(t, "other", nil)
+
+	pinned := NewApiCall(WithBaseUrl(ts.URL), WithCACert(caFile), WithPinnedKeys(SPKIPin(server.certificate)))
+	notPinned := NewApiCall(WithBaseUrl(ts.URL), WithCACert(caFile), WithPinnedKeys(SPKIPin(other.certificate)))
+	// server doesn't send its CA, which is only in verified chain
+	caPinned := NewApiCall(WithBaseUrl(ts.URL), WithCACert(caFile), WithPinnedKeys(SPKIPin(ca.certificate)))
+	ok, _ := pinned.Send("GET", "/", nil)
+	failed, _ := notPinned.Send("GET", "/", nil)
+	caOk, _ := caPinned.Send("GET", "/", nil)
+
+	assert.True(t, ok.IsOk())
+	assert.True(t, caOk.IsOk())
+	assert.False(t, failed.IsOk())
+	if assert.Len(t, failed.Errors.Items, 1) {
+		assert.Equal(t, "7", failed.Errors.Items[0].Code)
+		assert.Contains(t, failed.Errors.Items[0].Description, ErrPinMismatch.Error())
+	}
+}
+
+func TestTLSOptionErrorsAreReturnedBySend(t *testing.T) {
+	apicall := NewApiCall(
+		WithBaseUrl("https://localhost"),
+		WithCACert("does-not-exist.pem"),
+		WithMinTLSVersion(0x9999),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, response)
+	assert.Contains(t, err.Error(), "ca certificate")
+	assert.Len(t, apicall.errs, 2)
+}
+
+func TestTLSOptionsDontChangeOtherApiCall(t *testing.T) {
+	base := NewApiCall(WithMinTLSVersion(tls.VersionTLS12))
+	changed := WithMinTLSVersion(tls.VersionTLS13)(*base)
+
+	assert.Equal(t, uint16(tls.VersionTLS12), base.transport.config.tls.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS13), changed.transport.config.tls.MinVersion)
+}
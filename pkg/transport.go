@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+)
+
+// transportConfig hold options which change how connections
+// are made, each change build a new http.Transport
+type transportConfig struct {
+	tls      *tls.Config
+	proxies  []proxyRule
+	dial     DialFunc
+	resolver *resolver
+}
+
+// transport build http.Transport from its config once
+// and share it between every request of ApiCall
+type transport struct {
+	config    transportConfig
+	once      sync.Once
+	transport *http.Transport
+}
+
+// with return a new transport with config changed by change,
+// so transport of ApiCall isn't changed by options of another
+func (t *transport) with(change func(config *transportConfig)) *transport {
+	var config transportConfig
+	if t != nil {
+		config = t.config
+	}
+	if config.tls != nil {
+		config.tls = config.tls.Clone()
+	}
+	config.proxies = append([]proxyRule(nil), config.proxies...)
+	change(&config)
+	return &transport{config: config}
+}
+
+// roundTripper return http.Transport of config, http.DefaultTransport
+// is used when no option changed transport
+func (t *transport) roundTripper() http.RoundTripper {
+	if t == nil {
+		return http.DefaultTransport
+	}
+	t.once.Do(func() {
+		t.transport = http.DefaultTransport.(*http.Transport).Clone()
+		t.transport.TLSClientConfig = t.config.tls
+		if t.config.dial != nil {
+			t.transport.DialContext = t.config.dial
+		}
+		if t.config.resolver != nil {
+			t.transport.DialContext = t.config.resolver.dial(t.transport.DialContext)
+		}
+		if len(t.config.proxies) > 0 {
+			t.transport.Proxy = t.config.proxy
+		}
+	})
+	return t.transport
+}
+
+// tlsConfig return tls config of transport to be changed
+func (c *transportConfig) tlsConfig() *tls.Config {
+	if c.tls == nil {
+		c.tls = &tls.Config{}
+	}
+	return c.tls
+}
@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Validation is how strict the BaseStandard envelope
+// is checked when a response is received
+type Validation int
+
+const (
+	// ValidationNone it will not check the envelope
+	ValidationNone Validation = iota
+	// ValidationLenient it will check types of fields present and
+	// report each violation as Warning
+	ValidationLenient
+	// ValidationStrict it will also require items and auditInfo and
+	// report each violation as Errors, so response will not be ok
+	ValidationStrict
+)
+
+// auditInfoKinds is the expected json kind of each key of auditInfo
+var auditInfoKinds = [][2]string{
+	{"duration", "number"},
+	{"timestamp", "string"},
+	{"host", "string"},
+	{"clientIP", "string"},
+	{"ok", "boolean"},
+	{"statusCode", "number"},
+	{"operationId", "string"},
+	{"errors", "object"},
+	{"info", "object"},
+	{"warning", "object"},
+	{"total", "number"},
+}
+
+// WithValidation it will modified ApiCall.Validation field
+func WithValidation(validation Validation) Option {
+	return func(a ApiCall) *ApiCall {
+		if a.strict && (validation < ValidationNone || validation > ValidationStrict) {
+			a.addError(fmt.Errorf("validation: unknown validation %d", validation))
+			return &a
+		}
+		a.Validation = validation
+		return &a
+	}
+}
+
+// validateEnvelope it will check raw body against BaseStandard envelope
+// and append every violation found into baseResponse
+func validateEnvelope(baseResponse *BaseStandard, raw []byte, validation Validation) {
+	if validation == ValidationNone || !json.Valid(raw) {
+		return
+	}
+
+	violations := envelopeViolations(raw, validation == ValidationStrict)
+	for _, violation := range violations {
+		meta := Meta{Code: "validation", Description: violation}
+		if validation == ValidationStrict {
+			baseResponse.Errors.Items = append(baseResponse.Errors.Items, meta)
+			continue
+		}
+		baseResponse.Warning.Items = append(baseResponse.Warning.Items, meta)
+	}
+}
+
+func envelopeViolations(raw []byte, required bool) []string {
+	var violations []string
+	var envelope map[string]json.RawMessage
+	if kind := jsonKind(raw); kind != "object" {
+		return append(violations, fmt.Sprintf("body must be an object, got %s", kind))
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return append(violations, err.Error())
+	}
+
+	items, ok := envelope["items"]
+	if !ok && required {
+		violations = append(violations, "items is missing")
+	}
+	if kind := jsonKind(items); ok && kind != "array" && kind != "object" && kind != "null" {
+		violations = append(violations, fmt.Sprintf("items must be an array or object, got %s", kind))
+	}
+
+	auditInfo, ok := envelope["auditInfo"]
+	if !ok {
+		if required {
+			violations = append(violations, "auditInfo is missing")
+		}
+		return violations
+	}
+	if kind := jsonKind(auditInfo); kind != "object" {
+		return append(violations, fmt.Sprintf("auditInfo must be an object, got %s", kind))
+	}
+
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(auditInfo, &fields)
+	for _, kinds := range auditInfoKinds {
+		key, expected := kinds[0], kinds[1]
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if kind := jsonKind(value); kind != expected && kind != "null" {
+			violations = append(violations, fmt.Sprintf("auditInfo.%s must be a %s, got %s", key, expected, kind))
+			continue
+		}
+		if expected == "object" {
+			violations = append(violations, metaViolations("auditInfo."+key, value)...)
+		}
+	}
+
+	return violations
+}
+
+// metaViolations it will check errors, info and warning keys
+// of auditInfo, which must hold a list of Meta
+func metaViolations(path string, raw json.RawMessage) []string {
+	var violations []string
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(raw, &fields)
+
+	items, ok := fields["items"]
+	if !ok {
+		return violations
+	}
+	if kind := jsonKind(items); kind != "array" && kind != "null" {
+		return append(violations, fmt.Sprintf("%s.items must be an array, got %s", path, kind))
+	}
+
+	var metas []json.RawMessage
+	_ = json.Unmarshal(items, &metas)
+	for index, meta := range metas {
+		if kind := jsonKind(meta); kind != "object" {
+			violations = append(violations, fmt.Sprintf("%s.items[%d] must be an object, got %s", path, index, kind))
+			continue
+		}
+		var metaFields map[string]json.RawMessage
+		_ = json.Unmarshal(meta, &metaFields)
+		for _, key := range []string{"code", "description"} {
+			value, ok := metaFields[key]
+			if kind := jsonKind(value); ok && kind != "string" && kind != "null" {
+				violations = append(violations, fmt.Sprintf("%s.items[%d].%s must be a string, got %s", path, index, key, kind))
+			}
+		}
+	}
+
+	return violations
+}
+
+// jsonKind return which kind of json value raw is
+func jsonKind(raw []byte) string {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" {
+		return "empty"
+	}
+	switch trimmed[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
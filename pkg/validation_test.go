@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidationIsDisabledByDefault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":10}`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.Empty(t, response.AuditInfo.Errors.Items)
+	assert.Empty(t, response.AuditInfo.Warning.Items)
+}
+
+func TestValidationLenientReportWarning(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":[{"echo":"Hello World"}],"auditInfo":{"ok":"yes","errors":{"items":[{"code":200}]}}}`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithValidation(ValidationLenient),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "[validation]: auditInfo.ok must be a boolean, got string, [validation]: auditInfo.errors.items[0].code must be a string, got number", response.AuditInfo.Warning.String())
+}
+
+func TestValidationStrictReportErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"items":10}`))
+	}))
+	defer ts.Close()
+
+	apicall := NewApiCall(
+		WithBaseUrl(ts.URL),
+		WithValidation(ValidationStrict),
+	)
+	response, err := apicall.Send("GET", "/", nil)
+
+	assert.Nil(t, err)
+	assert.False(t, response.IsOk())
+	assert.Empty(t, response.AuditInfo.Warning.Items)
+	assert.Equal(t, "[validation]: items must be an array or object, got number, [validation]: auditInfo is missing", response.AuditInfo.Errors.String())
+}
+
+func TestEnvelopeViolations(t *testing.T) {
+	tables := []struct {
+		raw        string
+		required   bool
+		violations []string
+	}{
+		{`{"items":[],"auditInfo":{}}`, true, nil},
+		{`{}`, false, nil},
+		{`{}`, true, []string{"items is missing", "auditInfo is missing"}},
+		{`[]`, false, []string{"body must be an object, got array"}},
+		{`{"items":{},"auditInfo":[]}`, false, []string{"auditInfo must be an object, got array"}},
+		{`{"auditInfo":{"total":"1","warning":{"items":{}}}}`, false, []string{"auditInfo.warning.items must be an array, got object", "auditInfo.total must be a number, got string"}},
+		{`{"auditInfo":{"info":{"items":["info"]}}}`, false, []string{"auditInfo.info.items[0] must be an object, got string"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.raw, func(t *testing.T) {
+			assert.Equal(t, table.violations, envelopeViolations([]byte(table.raw), table.required))
+		})
+	}
+}